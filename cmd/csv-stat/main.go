@@ -4,16 +4,15 @@ import (
 	"context"
 	"encoding/csv"
 	"os"
-	"strings"
 	"sync"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
 	workerspool "github.com/Velnbur/uniswapv2-indexer/pkg/workers-pool"
 )
 
@@ -28,11 +27,11 @@ var (
 		Short('f').
 		Default("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f").
 		String()
-	// Ethereum node URI
-	node = kingpin.Flag("node", "Node URI").
+	// Ethereum nodes URIs, in order of preference
+	nodes = kingpin.Flag("node", "Node URI, could be repeated for failover").
 		Short('n').
 		Default("https://cloudflare-eth.com/").
-		String()
+		Strings()
 
 	loggerLevel = kingpin.Flag("log-level", "Log level").
 			Short('l').
@@ -40,11 +39,6 @@ var (
 			String()
 )
 
-// FIXME: this is a temporary solution, need to find a better way to handle rate
-// limit errors that alchemy makes, when we make too many requests. May be,
-// infura has better solutions for that
-const errRateLimitStr = "Your app has exceeded its compute units"
-
 func main() {
 	kingpin.Parse()
 
@@ -58,16 +52,31 @@ func main() {
 
 	log = log.Level(level)
 
-	client, err := ethclient.Dial(*node)
+	endpoints := make([]multiclient.EndpointConfig, len(*nodes))
+	for i, node := range *nodes {
+		endpoints[i] = multiclient.EndpointConfig{
+			URL:      node,
+			Priority: i,
+		}
+	}
+
+	client, err := multiclient.Dial(context.Background(), multiclient.Config{
+		Endpoints: endpoints,
+		Health:    multiclient.DefaultHealthConfig,
+		Retry:     multiclient.DefaultRetryConfig,
+	}, log)
 	if err != nil {
 		log.WithError(err).Fatal("failed to connecto to ethereum node")
 	}
+	defer client.Close()
 
 	factoryContract, err := contracts.NewUniswapV2Factory(
 		contracts.UniswapV2FactoryConfig{
-			Address: common.HexToAddress(*factory),
-			Client:  client,
-			Logger:  log,
+			UniswapV2FactoryParams: contracts.UniswapV2FactoryParams{
+				Address: common.HexToAddress(*factory),
+			},
+			Client: client,
+			Logger: log,
 		},
 	)
 	if err != nil {
@@ -145,7 +154,8 @@ func main() {
 			}
 
 			if err := f(ctx); err != nil {
-				if strings.Contains(err.Error(), errRateLimitStr) {
+				// client has already retried, so task is postponed
+				if multiclient.IsRateLimited(err) {
 					return workerspool.RetryError
 				}
 				return err
@@ -8,6 +8,8 @@ const (
 	PairCreationEvent EventType = iota + 1
 	BlockCreationEvent
 	ReservesUpdateEvent
+	RollbackEvent
+	PairActionEvent
 )
 
 type Event struct {
@@ -16,6 +18,8 @@ type Event struct {
 	BlockCreation  *BlockCreation
 	PairCreation   *PairCreation
 	ReservesUpdate *ReservesUpdate
+	Rollback       *Rollback
+	PairAction     *PairAction
 }
 
 type EventQueue interface {
@@ -0,0 +1,50 @@
+package channels
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+type PairActionType int
+
+const (
+	SwapAction PairActionType = iota + 1
+	MintAction
+	BurnAction
+)
+
+func (t PairActionType) String() string {
+	switch t {
+	case SwapAction:
+		return "swap"
+	case MintAction:
+		return "mint"
+	case BurnAction:
+		return "burn"
+	default:
+		return "unknown"
+	}
+}
+
+// PairAction - informational event of Swap, Mint or Burn log of
+// UniswapV2 pair. It doesn't change reserves, as every such log is
+// preceded by Sync log with resulting reserves.
+type PairAction struct {
+	Position data.LogPosition
+
+	Type    PairActionType
+	Address common.Address
+	Sender  common.Address
+	// To - receiver of tokens, zero for Mint
+	To common.Address
+
+	// Amount0In, Amount1In - tokens sent to the pair, for
+	// Mint it is liquidity that was added
+	Amount0In, Amount1In *big.Int
+	// Amount0Out, Amount1Out - tokens sent from the pair, for
+	// Burn it is liquidity that was removed
+	Amount0Out, Amount1Out *big.Int
+}
@@ -4,12 +4,24 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 )
 
 // PairCreation - event of pair creation in factory
 // contract
 type PairCreation struct {
-	Address            common.Address
+	// Position - position of the log that caused creation, or
+	// end of the block reserves were read at, if pair was loaded
+	// on start
+	Position data.LogPosition
+
+	Address common.Address
+	// Factory - factory that created the pair, its fee
+	// is applied to every swap in the pair
+	Factory            common.Address
+	Fee                math.Fee
 	Token0, Token1     common.Address
 	Reserve0, Reserve1 *big.Int
 }
@@ -4,15 +4,18 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
 )
 
-// ReservesUpdate - event of that reserves in UniswapV2 pair
-// were updated. All fields represent the change (difference)
-// between old and new state. All could be nil, but not all at
-// the same time.
+// ReservesUpdate - event of Sync log of UniswapV2 pair, that
+// carries reserves of the pair after the transaction. Reserves
+// are absolute, so update is applied only if it is newer than
+// the one pair already has.
 type ReservesUpdate struct {
-	Address        common.Address
-	Token0, Token1 common.Address
-	Reserve0Delta  *big.Int
-	Reserve1Delta  *big.Int
+	// Position - position of the Sync log
+	Position data.LogPosition
+
+	Address            common.Address
+	Reserve0, Reserve1 *big.Int
 }
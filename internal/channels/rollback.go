@@ -0,0 +1,8 @@
+package channels
+
+// Rollback - event of chain reorganization, all changes
+// made by logs after Block should be reverted
+type Rollback struct {
+	// Block - the last block that is still canonical
+	Block uint64
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/config"
 	"github.com/Velnbur/uniswapv2-indexer/internal/service"
+	"github.com/Velnbur/uniswapv2-indexer/internal/service/replay"
 	"github.com/alecthomas/kingpin"
 	"gitlab.com/distributed_lab/kit/kv"
 	"gitlab.com/distributed_lab/logan/v3"
@@ -30,7 +31,10 @@ func Run(args []string) bool {
 	runCmd := app.Command("run", "run command")
 	serviceCmd := runCmd.Command("service", "run service") // you can insert custom help
 
-	// custom commands go here...
+	replayCmd := app.Command("replay", "replay logs archive into indexer without node")
+	replayArchive := replayCmd.Flag("archive", "Directory of logs archive, configured one by default").String()
+	replaySnapshot := replayCmd.Flag("snapshot", "Graph snapshot file to start from, required if archive starts after stored snapshot").String()
+	replayOutput := replayCmd.Flag("output", "File to write resulting graph snapshot").Short('o').String()
 
 	cmd, err := app.Parse(args[1:])
 	if err != nil {
@@ -51,6 +55,21 @@ func Run(args []string) bool {
 	switch cmd {
 	case serviceCmd.FullCommand():
 		service.Run(ctx, cfg)
+	case replayCmd.FullCommand():
+		archive := *replayArchive
+		if archive == "" {
+			archive = cfg.ArchiveCfg().Dir
+		}
+
+		err := replay.Run(ctx, cfg, replay.Options{
+			Archive:  archive,
+			Snapshot: *replaySnapshot,
+			Output:   *replayOutput,
+		})
+		if err != nil {
+			log.WithError(err).Error("failed to replay archive")
+			return false
+		}
 	// handle any custom commands here in the same way
 	default:
 		log.Errorf("unknown command %s", cmd)
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/logarchive"
+)
+
+type Archiver interface {
+	ArchiveCfg() ArchiveCfg
+}
+
+// ArchiveCfg - settings of raw logs archive, that could
+// be replayed into indexer without Ethereum node
+type ArchiveCfg struct {
+	// Enabled - if true, listener writes every received log to archive
+	Enabled bool `fig:"enabled"`
+	// Dir - directory of archive segments
+	Dir string `fig:"dir"`
+	// SegmentSize - size of segment file in bytes,
+	// after which the next one is started
+	SegmentSize int64 `fig:"segment_size"`
+}
+
+func NewArchiveCfg(getter kv.Getter) Archiver {
+	return &archiveCfg{
+		getter: getter,
+	}
+}
+
+type archiveCfg struct {
+	getter kv.Getter
+	once   comfig.Once
+}
+
+const yamlArchiveKey = "archive"
+
+func (c *archiveCfg) ArchiveCfg() ArchiveCfg {
+	return c.once.Do(func() interface{} {
+		cfg := ArchiveCfg{
+			Dir:         "archive",
+			SegmentSize: logarchive.DefaultSegmentSize,
+		}
+
+		err := figure.Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, yamlArchiveKey)).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if cfg.Dir == "" || cfg.SegmentSize <= 0 {
+			panic(errors.New("archive dir should be set and segment size should be positive"))
+		}
+
+		return cfg
+	}).(ArchiveCfg)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type Backfiller interface {
+	BackfillCfg() BackfillCfg
+}
+
+// BackfillCfg - settings of historical logs loading, that is done
+// with eth_getLogs before live subscription is started
+type BackfillCfg struct {
+	// StartBlock - block from which logs are loaded if there is no
+	// saved progress, zero means that backfill is disabled
+	StartBlock uint64 `fig:"start_block"`
+	// BatchSize - initial number of blocks in one eth_getLogs request,
+	// it is reduced when node complains about results limit
+	BatchSize uint64 `fig:"batch_size"`
+	// MaxBatchSize - maximum number of blocks in one request, batch
+	// size grows up to it after successful requests
+	MaxBatchSize uint64 `fig:"max_batch_size"`
+}
+
+func NewBackfillCfg(getter kv.Getter) Backfiller {
+	return &backfillCfg{
+		getter: getter,
+	}
+}
+
+type backfillCfg struct {
+	getter kv.Getter
+	once   comfig.Once
+}
+
+const yamlBackfillKey = "backfill"
+
+func (c *backfillCfg) BackfillCfg() BackfillCfg {
+	return c.once.Do(func() interface{} {
+		cfg := BackfillCfg{
+			BatchSize:    2_000,
+			MaxBatchSize: 10_000,
+		}
+
+		err := figure.Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, yamlBackfillKey)).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if cfg.BatchSize == 0 || cfg.MaxBatchSize < cfg.BatchSize {
+			panic(errors.New("batch size should be positive and not greater than max batch size"))
+		}
+
+		return cfg
+	}).(BackfillCfg)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type Confirmer interface {
+	ConfirmationsCfg() ConfirmationsCfg
+}
+
+const (
+	// SafeTag, FinalizedTag - block tags of proof-of-stake
+	// chain, that could be used instead of depth
+	SafeTag      = "safe"
+	FinalizedTag = "finalized"
+)
+
+// ConfirmationsCfg - settings of delaying events until their blocks
+// are unlikely to be reorganized. Zero value means that events are
+// sent as soon as their logs are received.
+type ConfirmationsCfg struct {
+	// Depth - number of blocks after the block of event, after
+	// which event is sent to indexer
+	Depth uint64 `fig:"depth"`
+	// Tag - "safe" or "finalized", if set, events are sent when node
+	// reports their block with the tag, and Depth is ignored
+	Tag string `fig:"tag"`
+	// Pending - if true, all events are also sent immediately to
+	// the pending events queue, that is the freshest unconfirmed view
+	Pending bool `fig:"pending"`
+}
+
+// Enabled - returns true if events are delayed
+func (c ConfirmationsCfg) Enabled() bool {
+	return c.Depth != 0 || c.Tag != ""
+}
+
+func NewConfirmationsCfg(getter kv.Getter) Confirmer {
+	return &confirmationsCfg{
+		getter: getter,
+	}
+}
+
+type confirmationsCfg struct {
+	getter kv.Getter
+	once   comfig.Once
+}
+
+const yamlConfirmationsKey = "confirmations"
+
+func (c *confirmationsCfg) ConfirmationsCfg() ConfirmationsCfg {
+	return c.once.Do(func() interface{} {
+		var cfg ConfirmationsCfg
+
+		err := figure.Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, yamlConfirmationsKey)).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if cfg.Tag != "" && cfg.Tag != SafeTag && cfg.Tag != FinalizedTag {
+			panic(errors.From(errors.New("invalid confirmations tag"), logan.F{
+				"tag": cfg.Tag,
+			}))
+		}
+
+		return cfg
+	}).(ConfirmationsCfg)
+}
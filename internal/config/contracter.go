@@ -1,10 +1,18 @@
 package config
 
 import (
+	"reflect"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cast"
 	"gitlab.com/distributed_lab/figure"
 	"gitlab.com/distributed_lab/kit/comfig"
 	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 )
 
 type Contracter interface {
@@ -12,7 +20,9 @@ type Contracter interface {
 }
 
 type ContracterCfg struct {
-	Factory common.Address
+	// Factories - Uniswap V2 factory and factories of its
+	// forks, which pairs are indexed together
+	Factories []contracts.UniswapV2FactoryParams
 }
 
 func NewContracterCfg(getter kv.Getter) Contracter {
@@ -27,7 +37,55 @@ type contracter struct {
 }
 
 type contracterCfg struct {
-	Factory string `fig:"factory,required"`
+	// Factory - deprecated single Uniswap V2 factory,
+	// that is used if Factories are not set
+	Factory   string       `fig:"factory"`
+	Factories []factoryCfg `fig:"factories"`
+}
+
+type factoryCfg struct {
+	Name           string `fig:"name,required"`
+	Address        string `fig:"address,required"`
+	FeeNumerator   uint64 `fig:"fee_numerator"`
+	FeeDenominator uint64 `fig:"fee_denominator"`
+	InitCodeHash   string `fig:"init_code_hash"`
+}
+
+// uniswapV2InitCodeHash - keccak256 of Uniswap V2 pair creation code
+const uniswapV2InitCodeHash = "0x96e8ac4277198ff8b6f785478aa9a39f403cb768dd02cbee326c3e7da348845f"
+
+var factoriesHooks = figure.Hooks{
+	"[]config.factoryCfg": func(value interface{}) (reflect.Value, error) {
+		rawFactories, err := cast.ToSliceE(value)
+		if err != nil {
+			return reflect.Value{}, errors.Wrap(err, "failed to parse factories list")
+		}
+
+		factories := make([]factoryCfg, len(rawFactories))
+
+		for i, rawFactory := range rawFactories {
+			factory, err := cast.ToStringMapE(rawFactory)
+			if err != nil {
+				return reflect.Value{}, errors.Wrap(err, "failed to parse factory", logan.F{
+					"index": i,
+				})
+			}
+
+			factories[i] = factoryCfg{
+				FeeNumerator:   math.FeeNumerator,
+				FeeDenominator: math.FeeDenominator,
+			}
+
+			err = figure.Out(&factories[i]).From(factory).Please()
+			if err != nil {
+				return reflect.Value{}, errors.Wrap(err, "failed to figure out factory", logan.F{
+					"index": i,
+				})
+			}
+		}
+
+		return reflect.ValueOf(factories), nil
+	},
 }
 
 const yamlContracterKey = "contracts"
@@ -37,14 +95,50 @@ func (c *contracter) ContracterCfg() ContracterCfg {
 		var cfg contracterCfg
 
 		err := figure.Out(&cfg).
+			With(figure.BaseHooks, factoriesHooks).
 			From(kv.MustGetStringMap(c.getter, yamlContracterKey)).
 			Please()
 		if err != nil {
 			panic(err)
 		}
 
+		if len(cfg.Factories) == 0 && cfg.Factory != "" {
+			cfg.Factories = []factoryCfg{{
+				Name:           "uniswapv2",
+				Address:        cfg.Factory,
+				FeeNumerator:   math.FeeNumerator,
+				FeeDenominator: math.FeeDenominator,
+				InitCodeHash:   uniswapV2InitCodeHash,
+			}}
+		}
+
+		if len(cfg.Factories) == 0 {
+			panic(errors.New("no factories are configured"))
+		}
+
+		factories := make([]contracts.UniswapV2FactoryParams, len(cfg.Factories))
+		for i, factory := range cfg.Factories {
+			if factory.FeeDenominator == 0 || factory.FeeNumerator > factory.FeeDenominator {
+				panic(errors.From(errors.New("invalid factory fee"), logan.F{
+					"factory":         factory.Name,
+					"fee_numerator":   factory.FeeNumerator,
+					"fee_denominator": factory.FeeDenominator,
+				}))
+			}
+
+			factories[i] = contracts.UniswapV2FactoryParams{
+				Name:    factory.Name,
+				Address: common.HexToAddress(factory.Address),
+				Fee: math.Fee{
+					Numerator:   factory.FeeNumerator,
+					Denominator: factory.FeeDenominator,
+				},
+				InitCodeHash: common.HexToHash(factory.InitCodeHash),
+			}
+		}
+
 		return ContracterCfg{
-			Factory: common.HexToAddress(cfg.Factory),
+			Factories: factories,
 		}
 	}).(ContracterCfg)
 }
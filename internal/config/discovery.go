@@ -0,0 +1,146 @@
+package config
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cast"
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type Discoverer interface {
+	DiscoveryCfg() DiscoveryCfg
+}
+
+// DiscoveryCfg - settings of pairs discovery, that enumerates all
+// pairs of factories instead of pairs of configured tokens
+type DiscoveryCfg struct {
+	// Enabled - if false, only pairs between configured tokens
+	// are requested from factories
+	Enabled bool
+	// Workers - number of batches of pairs loaded concurrently,
+	// progress is saved after every round of them
+	Workers int
+	// BatchSize - number of pairs loaded with one batch of calls
+	BatchSize uint64
+	// MinReserves - pairs with reserve of token less than its minimum
+	// are skipped, in units of the token, as tokens have different
+	// decimals. Reserves of tokens that are not listed are not checked.
+	MinReserves map[common.Address]*big.Int
+	// AllowedTokens - only pairs between these tokens are indexed,
+	// empty means that all pairs are indexed
+	AllowedTokens []common.Address
+}
+
+func NewDiscoveryCfg(getter kv.Getter) Discoverer {
+	return &discoveryCfg{
+		getter: getter,
+	}
+}
+
+type discoveryCfg struct {
+	getter kv.Getter
+	once   comfig.Once
+}
+
+type discoveryRawCfg struct {
+	Enabled       bool     `fig:"enabled"`
+	Workers       int      `fig:"workers"`
+	BatchSize     uint64   `fig:"batch_size"`
+	AllowedTokens []string `fig:"allowed_tokens"`
+}
+
+const yamlDiscoveryKey = "discovery"
+
+// yamlMinReservesKey - minimal reserves of pairs by token address
+const yamlMinReservesKey = "min_reserves"
+
+func (c *discoveryCfg) DiscoveryCfg() DiscoveryCfg {
+	return c.once.Do(func() interface{} {
+		raw := discoveryRawCfg{
+			Workers:   8,
+			BatchSize: 500,
+		}
+
+		rawCfg := kv.MustGetStringMap(c.getter, yamlDiscoveryKey)
+
+		err := figure.Out(&raw).
+			From(rawCfg).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		minReserves, err := parseMinReserves(rawCfg[yamlMinReservesKey])
+		if err != nil {
+			panic(errors.Wrap(err, "failed to parse min reserves"))
+		}
+
+		if raw.Workers <= 0 || raw.BatchSize == 0 {
+			panic(errors.New("workers and batch size should be positive"))
+		}
+
+		allowed := make([]common.Address, len(raw.AllowedTokens))
+		for i, token := range raw.AllowedTokens {
+			if !common.IsHexAddress(token) {
+				panic(errors.From(errors.New("invalid allowed token address"), logan.F{
+					"token": token,
+				}))
+			}
+
+			allowed[i] = common.HexToAddress(token)
+		}
+
+		return DiscoveryCfg{
+			Enabled:       raw.Enabled,
+			Workers:       raw.Workers,
+			BatchSize:     raw.BatchSize,
+			MinReserves:   minReserves,
+			AllowedTokens: allowed,
+		}
+	}).(DiscoveryCfg)
+}
+
+func parseMinReserves(value interface{}) (map[common.Address]*big.Int, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	rawReserves, err := cast.ToStringMapE(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse reserves map")
+	}
+
+	reserves := make(map[common.Address]*big.Int, len(rawReserves))
+	for token, rawReserve := range rawReserves {
+		if !common.IsHexAddress(token) {
+			return nil, errors.From(errors.New("invalid token address"), logan.F{
+				"token": token,
+			})
+		}
+
+		// big numbers should be quoted, as YAML parses them as floats
+		amount, err := cast.ToStringE(rawReserve)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse reserve", logan.F{
+				"token": token,
+			})
+		}
+
+		reserve, ok := new(big.Int).SetString(amount, 10)
+		if !ok || reserve.Sign() < 0 {
+			return nil, errors.From(errors.New("invalid reserve"), logan.F{
+				"token":   token,
+				"reserve": amount,
+			})
+		}
+
+		reserves[common.HexToAddress(token)] = reserve
+	}
+
+	return reserves, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+func Test_DiscoveryCfg(t *testing.T) {
+	t.Run("min reserves from sample config are parsed", func(t *testing.T) {
+		cfg := NewDiscoveryCfg(kv.NewViperFile("../../config.yaml")).DiscoveryCfg()
+
+		weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+
+		require.Len(t, cfg.MinReserves, 2)
+		require.Equal(t, "1000000000000000000", cfg.MinReserves[weth].String())
+		require.Equal(t, "1000000000", cfg.MinReserves[usdc].String())
+	})
+
+	t.Run("invalid reserves", func(t *testing.T) {
+		_, err := parseMinReserves(map[string]interface{}{"weth": "1"})
+		require.Error(t, err)
+
+		_, err = parseMinReserves(map[string]interface{}{
+			"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2": "1e18",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("no reserves", func(t *testing.T) {
+		reserves, err := parseMinReserves(nil)
+		require.NoError(t, err)
+		require.Nil(t, reserves)
+
+		reserves, err = parseMinReserves(map[string]interface{}{
+			"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2": 1000,
+		})
+		require.NoError(t, err)
+		require.Zero(t, reserves[common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")].Cmp(big.NewInt(1000)))
+	})
+}
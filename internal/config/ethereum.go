@@ -1,57 +1,216 @@
 package config
 
 import (
-	"github.com/ethereum/go-ethereum/ethclient"
+	"context"
+	"reflect"
+	"time"
+
 	"github.com/pkg/errors"
+	"github.com/spf13/cast"
 	"gitlab.com/distributed_lab/figure"
 	"gitlab.com/distributed_lab/kit/comfig"
 	"gitlab.com/distributed_lab/kit/kv"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
 )
 
 type Ethereumer interface {
 	EthereumCfg() EthereumCfg
-	EthereumClient() *ethclient.Client
+	EthereumClient() *multiclient.Client
 }
 
 type EthereumCfg struct {
-	Node string `fig:"node,required"`
+	// Node - deprecated single node, that is used if Endpoints are not set
+	Node string `fig:"node"`
+	// Endpoints - nodes that are used with failover, preferring
+	// healthy ones with lower priority
+	Endpoints []multiclient.EndpointConfig `fig:"endpoints"`
+	// HealthCheckInterval, MaxHeadLag, MaxErrorRate, MaxLatency -
+	// thresholds after which endpoint is considered unhealthy
+	HealthCheckInterval time.Duration `fig:"health_check_interval"`
+	MaxHeadLag          uint64        `fig:"max_head_lag"`
+	MaxErrorRate        float64       `fig:"max_error_rate"`
+	MaxLatency          time.Duration `fig:"max_latency"`
+	// MaxRetries, RetryMinDelay, RetryMaxDelay - retries of requests
+	// that failed with all endpoints or were throttled by them
+	MaxRetries    int           `fig:"max_retries"`
+	RetryMinDelay time.Duration `fig:"retry_min_delay"`
+	RetryMaxDelay time.Duration `fig:"retry_max_delay"`
+	// ReorgDepth - number of recent blocks which could be reorganized,
+	// changes made by them are kept to be reverted
+	ReorgDepth uint64 `fig:"reorg_depth"`
+	// ReconnectMinDelay, ReconnectMaxDelay - bounds of delay between
+	// attempts to resubscribe after subscription is dropped
+	ReconnectMinDelay time.Duration `fig:"reconnect_min_delay"`
+	ReconnectMaxDelay time.Duration `fig:"reconnect_max_delay"`
+	// PollInterval - how often new logs are requested if none
+	// of nodes supports subscriptions
+	PollInterval time.Duration `fig:"poll_interval"`
+}
+
+func (c EthereumCfg) ClientConfig() multiclient.Config {
+	return multiclient.Config{
+		Endpoints: c.Endpoints,
+		Health: multiclient.HealthConfig{
+			CheckInterval: c.HealthCheckInterval,
+			MaxHeadLag:    c.MaxHeadLag,
+			MaxErrorRate:  c.MaxErrorRate,
+			MaxLatency:    c.MaxLatency,
+		},
+		Retry: multiclient.RetryConfig{
+			MaxRetries: c.MaxRetries,
+			MinDelay:   c.RetryMinDelay,
+			MaxDelay:   c.RetryMaxDelay,
+		},
+	}
 }
 
-func NewEthereumCfg(getter kv.Getter) Ethereumer {
+func NewEthereumCfg(getter kv.Getter, logger comfig.Logger) Ethereumer {
 	return &ethereumCfg{
 		getter: getter,
+		logger: logger,
 	}
 }
 
 type ethereumCfg struct {
-	getter kv.Getter
-	once   comfig.Once
+	getter     kv.Getter
+	logger     comfig.Logger
+	once       comfig.Once
+	clientOnce comfig.Once
+}
+
+type endpointCfg struct {
+	URL      string `fig:"url,required"`
+	Priority int    `fig:"priority"`
+	// RequestsPerSecond, ComputeUnitsPerSecond - limits of
+	// provider plan, zero means that there is no limit
+	RequestsPerSecond     float64 `fig:"requests_per_second"`
+	ComputeUnitsPerSecond int     `fig:"compute_units_per_second"`
+}
+
+// yamlMethodCostsKey - compute units of methods of endpoint,
+// methods that are not listed cost one unit
+const yamlMethodCostsKey = "method_costs"
+
+var endpointsHooks = figure.Hooks{
+	"[]multiclient.EndpointConfig": func(value interface{}) (reflect.Value, error) {
+		rawEndpoints, err := cast.ToSliceE(value)
+		if err != nil {
+			return reflect.Value{}, errors.Wrap(err, "failed to parse endpoints list")
+		}
+
+		endpoints := make([]multiclient.EndpointConfig, len(rawEndpoints))
+
+		for i, rawEndpoint := range rawEndpoints {
+			endpoint, err := cast.ToStringMapE(rawEndpoint)
+			if err != nil {
+				return reflect.Value{}, errors.Wrapf(err, "failed to parse endpoint %d", i)
+			}
+
+			var cfg endpointCfg
+
+			err = figure.Out(&cfg).From(endpoint).Please()
+			if err != nil {
+				return reflect.Value{}, errors.Wrapf(err, "failed to figure out endpoint %d", i)
+			}
+
+			var rawCosts map[string]interface{}
+			if value, ok := endpoint[yamlMethodCostsKey]; ok {
+				rawCosts, err = cast.ToStringMapE(value)
+				if err != nil {
+					return reflect.Value{}, errors.Wrapf(err, "failed to parse method costs of endpoint %d", i)
+				}
+			}
+
+			costs := make(map[string]int, len(rawCosts))
+			for method, rawCost := range rawCosts {
+				cost, err := cast.ToIntE(rawCost)
+				if err != nil || cost <= 0 {
+					return reflect.Value{}, errors.Errorf("invalid cost of %s method of endpoint %d", method, i)
+				}
+
+				costs[method] = cost
+			}
+
+			endpoints[i] = multiclient.EndpointConfig{
+				URL:      cfg.URL,
+				Priority: cfg.Priority,
+				RateLimit: multiclient.RateLimitConfig{
+					RequestsPerSecond:     cfg.RequestsPerSecond,
+					ComputeUnitsPerSecond: cfg.ComputeUnitsPerSecond,
+					MethodCosts:           costs,
+				},
+			}
+		}
+
+		return reflect.ValueOf(endpoints), nil
+	},
 }
 
 const yamlEthereumerKey = "ethereum"
 
 func (c *ethereumCfg) EthereumCfg() EthereumCfg {
 	return c.once.Do(func() interface{} {
-		var cfg EthereumCfg
+		cfg := EthereumCfg{
+			HealthCheckInterval: multiclient.DefaultHealthConfig.CheckInterval,
+			MaxHeadLag:          multiclient.DefaultHealthConfig.MaxHeadLag,
+			MaxErrorRate:        multiclient.DefaultHealthConfig.MaxErrorRate,
+			MaxLatency:          multiclient.DefaultHealthConfig.MaxLatency,
+			MaxRetries:          multiclient.DefaultRetryConfig.MaxRetries,
+			RetryMinDelay:       multiclient.DefaultRetryConfig.MinDelay,
+			RetryMaxDelay:       multiclient.DefaultRetryConfig.MaxDelay,
+			ReorgDepth:          64,
+			ReconnectMinDelay:   time.Second,
+			ReconnectMaxDelay:   time.Minute,
+			PollInterval:        12 * time.Second,
+		}
 
 		err := figure.Out(&cfg).
+			With(figure.BaseHooks, endpointsHooks).
 			From(kv.MustGetStringMap(c.getter, yamlEthereumerKey)).
 			Please()
 		if err != nil {
 			panic(err)
 		}
 
+		if len(cfg.Endpoints) == 0 && cfg.Node != "" {
+			cfg.Endpoints = []multiclient.EndpointConfig{{URL: cfg.Node}}
+		}
+
+		if len(cfg.Endpoints) == 0 {
+			panic(errors.New("no ethereum endpoints are configured"))
+		}
+
+		if cfg.HealthCheckInterval <= 0 {
+			panic(errors.New("health check interval should be positive"))
+		}
+
+		if cfg.MaxRetries < 0 || cfg.RetryMinDelay <= 0 || cfg.RetryMaxDelay < cfg.RetryMinDelay {
+			panic(errors.New("retries should not be negative, and retry min delay should be positive and not greater than max delay"))
+		}
+
+		if cfg.ReconnectMinDelay <= 0 || cfg.ReconnectMaxDelay < cfg.ReconnectMinDelay {
+			panic(errors.New("reconnect min delay should be positive and not greater than max delay"))
+		}
+
+		if cfg.PollInterval <= 0 {
+			panic(errors.New("poll interval should be positive"))
+		}
+
 		return cfg
 	}).(EthereumCfg)
 }
 
-func (c *ethereumCfg) EthereumClient() *ethclient.Client {
-	return c.once.Do(func() interface{} {
-		client, err := ethclient.Dial(c.EthereumCfg().Node)
+func (c *ethereumCfg) EthereumClient() *multiclient.Client {
+	return c.clientOnce.Do(func() interface{} {
+		client, err := multiclient.Dial(
+			context.Background(), c.EthereumCfg().ClientConfig(),
+			c.logger.Log().WithField("service", "ethereum"),
+		)
 		if err != nil {
-			panic(errors.Wrap(err, "failed to connect to ethereum node"))
+			panic(errors.Wrap(err, "failed to connect to ethereum nodes"))
 		}
 
 		return client
-	}).(*ethclient.Client)
+	}).(*multiclient.Client)
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type Indexerer interface {
+	IndexerCfg() IndexerCfg
+}
+
+// IndexerCfg - limits of pathes enumeration and graph persistence
+// settings. Zero value of any limit means that it is disabled.
+type IndexerCfg struct {
+	// MaxHops - maximum number of swaps in one path
+	MaxHops int `fig:"max_hops"`
+	// MaxPathesPerPair - maximum number of pathes stored for
+	// every (token in, token out) pair
+	MaxPathesPerPair int `fig:"max_pathes_per_pair"`
+	// MaxPathes - maximum number of pathes stored in total
+	MaxPathes int `fig:"max_pathes"`
+	// Timeout - time budget for one indexing, or for finding
+	// pathes through one added pair
+	Timeout time.Duration `fig:"timeout"`
+	// SnapshotInterval - how often graph snapshot is saved, zero
+	// means that it is saved only on shutdown
+	SnapshotInterval time.Duration `fig:"snapshot_interval"`
+}
+
+func NewIndexerCfg(getter kv.Getter) Indexerer {
+	return &indexerCfg{
+		getter: getter,
+	}
+}
+
+type indexerCfg struct {
+	getter kv.Getter
+	once   comfig.Once
+}
+
+const yamlIndexerKey = "indexer"
+
+func (c *indexerCfg) IndexerCfg() IndexerCfg {
+	return c.once.Do(func() interface{} {
+		cfg := IndexerCfg{
+			MaxHops:          3,
+			MaxPathesPerPair: 16,
+			MaxPathes:        1_000_000,
+			Timeout:          30 * time.Second,
+			SnapshotInterval: time.Minute,
+		}
+
+		err := figure.Out(&cfg).
+			From(kv.MustGetStringMap(c.getter, yamlIndexerKey)).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return cfg
+	}).(IndexerCfg)
+}
@@ -11,9 +11,16 @@ type Config interface {
 	comfig.Logger
 	comfig.Listenerer
 
+	Archiver
+	Backfiller
+	Confirmer
 	Contracter
+	Discoverer
 	Ethereumer
+	Indexerer
+	Multicaller
 	Queuer
+	Viewer
 
 	Redis() *redis.Client
 	Tokens() []*contracts.ERC20
@@ -24,21 +31,38 @@ type config struct {
 	comfig.Listenerer
 	getter kv.Getter
 
+	Archiver
+	Backfiller
+	Confirmer
 	Contracter
+	Discoverer
 	Ethereumer
+	Indexerer
+	Multicaller
 	Queuer
+	Viewer
 
 	redis  comfig.Once
 	tokens comfig.Once
 }
 
 func New(getter kv.Getter) Config {
+	logger := comfig.NewLogger(getter, comfig.LoggerOpts{})
+	ethereumer := NewEthereumCfg(getter, logger)
+
 	return &config{
-		getter:     getter,
-		Listenerer: comfig.NewListenerer(getter),
-		Logger:     comfig.NewLogger(getter, comfig.LoggerOpts{}),
-		Contracter: NewContracterCfg(getter),
-		Ethereumer: NewEthereumCfg(getter),
-		Queuer:     &queuer{},
+		getter:      getter,
+		Listenerer:  comfig.NewListenerer(getter),
+		Logger:      logger,
+		Archiver:    NewArchiveCfg(getter),
+		Backfiller:  NewBackfillCfg(getter),
+		Confirmer:   NewConfirmationsCfg(getter),
+		Contracter:  NewContracterCfg(getter),
+		Discoverer:  NewDiscoveryCfg(getter),
+		Ethereumer:  ethereumer,
+		Indexerer:   NewIndexerCfg(getter),
+		Multicaller: NewMulticallCfg(getter, ethereumer, logger),
+		Queuer:      &queuer{},
+		Viewer:      &viewer{},
 	}
 }
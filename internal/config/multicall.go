@@ -0,0 +1,98 @@
+package config
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"gitlab.com/distributed_lab/figure"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multicall"
+)
+
+type Multicaller interface {
+	MulticallCfg() MulticallCfg
+	Multicall() *multicall.Multicall
+}
+
+// MulticallCfg - settings of batching of contract calls, that
+// are used to load pairs on startup and discovery
+type MulticallCfg struct {
+	// Address - Multicall3 contract, if there is no contract at it,
+	// calls are sent in JSON-RPC batches instead
+	Address common.Address
+	// BatchSize - maximum number of calls in one request
+	BatchSize int
+}
+
+func NewMulticallCfg(getter kv.Getter, ethereumer Ethereumer, logger comfig.Logger) Multicaller {
+	return &multicallCfg{
+		getter:     getter,
+		ethereumer: ethereumer,
+		logger:     logger,
+	}
+}
+
+type multicallCfg struct {
+	getter     kv.Getter
+	ethereumer Ethereumer
+	logger     comfig.Logger
+
+	once          comfig.Once
+	multicallOnce comfig.Once
+}
+
+type multicallRawCfg struct {
+	Address   string `fig:"address"`
+	BatchSize int    `fig:"batch_size"`
+}
+
+const yamlMulticallKey = "multicall"
+
+func (c *multicallCfg) MulticallCfg() MulticallCfg {
+	return c.once.Do(func() interface{} {
+		raw := multicallRawCfg{
+			Address:   multicall.Multicall3Address.Hex(),
+			BatchSize: 500,
+		}
+
+		err := figure.Out(&raw).
+			From(kv.MustGetStringMap(c.getter, yamlMulticallKey)).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if !common.IsHexAddress(raw.Address) {
+			panic(errors.From(errors.New("invalid multicall address"), logan.F{
+				"address": raw.Address,
+			}))
+		}
+
+		if raw.BatchSize <= 0 {
+			panic(errors.New("batch size should be positive"))
+		}
+
+		return MulticallCfg{
+			Address:   common.HexToAddress(raw.Address),
+			BatchSize: raw.BatchSize,
+		}
+	}).(MulticallCfg)
+}
+
+func (c *multicallCfg) Multicall() *multicall.Multicall {
+	return c.multicallOnce.Do(func() interface{} {
+		cfg := c.MulticallCfg()
+
+		mc, err := multicall.New(
+			c.ethereumer.EthereumClient(), cfg.Address, cfg.BatchSize,
+			c.logger.Log().WithField("service", "multicall"),
+		)
+		if err != nil {
+			panic(errors.Wrap(err, "failed to create multicall"))
+		}
+
+		return mc
+	}).(*multicall.Multicall)
+}
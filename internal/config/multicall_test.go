@@ -0,0 +1,18 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/kit/kv"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multicall"
+)
+
+func Test_MulticallCfg(t *testing.T) {
+	t.Run("address from sample config is parsed", func(t *testing.T) {
+		cfg := NewMulticallCfg(kv.NewViperFile("../../config.yaml"), nil, nil)
+
+		require.Equal(t, multicall.Multicall3Address, cfg.MulticallCfg().Address)
+	})
+}
@@ -7,10 +7,15 @@ import (
 
 type Queuer interface {
 	EventsQueue() channels.EventQueue
+	// PendingEventsQueue - all events as soon as they are received,
+	// it is filled only if pending view is enabled in confirmations,
+	// and is applied by pending indexer
+	PendingEventsQueue() channels.EventQueue
 }
 
 type queuer struct {
-	onceEvents comfig.Once
+	onceEvents        comfig.Once
+	oncePendingEvents comfig.Once
 }
 
 func (q *queuer) EventsQueue() channels.EventQueue {
@@ -18,3 +23,9 @@ func (q *queuer) EventsQueue() channels.EventQueue {
 		return channels.NewEventChan()
 	}).(channels.EventQueue)
 }
+
+func (q *queuer) PendingEventsQueue() channels.EventQueue {
+	return q.oncePendingEvents.Do(func() interface{} {
+		return channels.NewEventChan()
+	}).(channels.EventQueue)
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/kit/comfig"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+)
+
+// Viewer - graphs of indexers, that are shared with
+// API in the same way as events queues are
+type Viewer interface {
+	// QuotesView - graph of indexer, that API quotes swaps with
+	QuotesView() *providers.QuotesMemoryProvider
+	// PendingQuotesView - graph of indexer of pending events queue,
+	// it is set only if pending view is enabled in confirmations
+	PendingQuotesView() *providers.QuotesMemoryProvider
+}
+
+type viewer struct {
+	onceQuotes        comfig.Once
+	oncePendingQuotes comfig.Once
+}
+
+func (v *viewer) QuotesView() *providers.QuotesMemoryProvider {
+	return v.onceQuotes.Do(func() interface{} {
+		return providers.NewQuotesMemoryProvider()
+	}).(*providers.QuotesMemoryProvider)
+}
+
+func (v *viewer) PendingQuotesView() *providers.QuotesMemoryProvider {
+	return v.oncePendingQuotes.Do(func() interface{} {
+		return providers.NewQuotesMemoryProvider()
+	}).(*providers.QuotesMemoryProvider)
+}
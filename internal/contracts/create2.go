@@ -0,0 +1,37 @@
+package contracts
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SortTokens - returns tokens in the order they are stored by pair
+func SortTokens(tokenA, tokenB common.Address) (common.Address, common.Address) {
+	if bytes.Compare(tokenA.Bytes(), tokenB.Bytes()) > 0 {
+		return tokenB, tokenA
+	}
+
+	return tokenA, tokenB
+}
+
+// ComputePairAddress - returns address of the pair that factory creates
+// for tokens with CREATE2, salted by keccak256 of sorted tokens
+func ComputePairAddress(factory common.Address, initCodeHash common.Hash, tokenA, tokenB common.Address) common.Address {
+	token0, token1 := SortTokens(tokenA, tokenB)
+	salt := crypto.Keccak256Hash(token0.Bytes(), token1.Bytes())
+
+	return crypto.CreateAddress2(factory, salt, initCodeHash.Bytes())
+}
+
+// PairAddress - computes address of the pair for tokens without requests
+// to node, false is returned if init code hash of factory is unknown.
+// Pair with computed address could be not created yet.
+func (u *UniswapV2Factory) PairAddress(tokenA, tokenB common.Address) (common.Address, bool) {
+	if u.InitCodeHash == (common.Hash{}) {
+		return common.Address{}, false
+	}
+
+	return ComputePairAddress(u.Address, u.InitCodeHash, tokenA, tokenB), true
+}
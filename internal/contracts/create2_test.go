@@ -0,0 +1,69 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ComputePairAddress(t *testing.T) {
+	var (
+		factory      = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+		initCodeHash = common.HexToHash("0x96e8ac4277198ff8b6f785478aa9a39f403cb768dd02cbee326c3e7da348845f")
+		usdc         = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+		weth         = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		pair         = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	)
+
+	t.Run("address of mainnet pair", func(t *testing.T) {
+		require.Equal(t, pair, ComputePairAddress(factory, initCodeHash, usdc, weth))
+	})
+
+	t.Run("order of tokens doesn't matter", func(t *testing.T) {
+		require.Equal(t, pair, ComputePairAddress(factory, initCodeHash, weth, usdc))
+	})
+}
+
+func Test_GetPools(t *testing.T) {
+	var (
+		factory = UniswapV2FactoryParams{
+			Name:         "uniswapv2",
+			Address:      common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f"),
+			InitCodeHash: common.HexToHash("0x96e8ac4277198ff8b6f785478aa9a39f403cb768dd02cbee326c3e7da348845f"),
+		}
+		usdc = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+		weth = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		pair = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	)
+
+	t.Run("pairs are computed without node", func(t *testing.T) {
+		// factory has neither client nor multicall, so
+		// any request would fail
+		u, err := NewUniswapV2Factory(UniswapV2FactoryConfig{UniswapV2FactoryParams: factory})
+		require.NoError(t, err)
+
+		pairs, err := u.GetPools(context.Background(), [][2]common.Address{{weth, usdc}})
+		require.NoError(t, err)
+		require.Len(t, pairs, 1)
+		require.Equal(t, pair, pairs[0].Address)
+		require.Equal(t, usdc, pairs[0].token0)
+		require.Equal(t, weth, pairs[0].token1)
+
+		single, err := u.GetPool(context.Background(), weth, usdc)
+		require.NoError(t, err)
+		require.Equal(t, pair, single.Address)
+	})
+
+	t.Run("pairs are requested without init code hash", func(t *testing.T) {
+		u, err := NewUniswapV2Factory(UniswapV2FactoryConfig{UniswapV2FactoryParams: UniswapV2FactoryParams{
+			Name:    factory.Name,
+			Address: factory.Address,
+		}})
+		require.NoError(t, err)
+
+		_, err = u.GetPools(context.Background(), [][2]common.Address{{weth, usdc}})
+		require.Equal(t, errNoMulticall, err)
+	})
+}
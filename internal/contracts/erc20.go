@@ -5,7 +5,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 
 	"github.com/Velnbur/uniswapv2-indexer/generated/erc20"
@@ -14,7 +13,7 @@ import (
 
 type Erc20Config struct {
 	Address  common.Address
-	Client   *ethclient.Client
+	Client   bind.ContractBackend
 	Provider providers.Erc20Provider
 }
 
@@ -1,34 +1,71 @@
 package contracts
 
 import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 	"gitlab.com/distributed_lab/logan/v3"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multicall"
 )
 
 type UniswapV2 struct {
-	Factory *UniswapV2Factory
-	Pairs   *UniswapV2PairsMap
+	// Factories - Uniswap V2 factory and factories of its
+	// forks, which pairs are indexed together
+	Factories []*UniswapV2Factory
+	Pairs     *UniswapV2PairsMap
+
+	multicall *multicall.Multicall
 }
 
 func NewUniswapV2(
-	factoryAddr common.Address, client *ethclient.Client, logger *logan.Entry,
+	factories []UniswapV2FactoryParams, client bind.ContractBackend,
+	multicall *multicall.Multicall, logger *logan.Entry,
 	factoryProvider providers.UniswapV2FactoryProvider,
 	pairProvider providers.UniswapV2PairProvider,
 	erc20 providers.Erc20Provider,
 ) (*UniswapV2, error) {
-	factory, err := NewUniswapV2Factory(UniswapV2FactoryConfig{
-		factoryAddr, client, logger,
-		factoryProvider, pairProvider, erc20,
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create UniswapV2Factory")
+	uniswapV2 := &UniswapV2{
+		Factories: make([]*UniswapV2Factory, 0, len(factories)),
+		Pairs:     NewPairsMap(),
+		multicall: multicall,
+	}
+
+	for _, params := range factories {
+		factory, err := NewUniswapV2Factory(UniswapV2FactoryConfig{
+			params, client, logger.WithField("factory", params.Name), multicall,
+			factoryProvider, pairProvider, erc20,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s factory", params.Name)
+		}
+
+		uniswapV2.Factories = append(uniswapV2.Factories, factory)
+	}
+
+	return uniswapV2, nil
+}
+
+// Factory - returns factory by its address, or nil if
+// there is no such factory
+func (u *UniswapV2) Factory(address common.Address) *UniswapV2Factory {
+	for _, factory := range u.Factories {
+		if factory.Address == address {
+			return factory
+		}
 	}
-	return &UniswapV2{
-		Factory: factory,
-		Pairs:   NewPairsMap(),
-	}, nil
+
+	return nil
+}
+
+// FactoriesAddresses - returns addresses of all factories
+func (u *UniswapV2) FactoriesAddresses() []common.Address {
+	addresses := make([]common.Address, len(u.Factories))
+
+	for i, factory := range u.Factories {
+		addresses[i] = factory.Address
+	}
+
+	return addresses
 }
@@ -0,0 +1,342 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	uniswapv2factory "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-factory"
+	uniswapv2pair "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-pair"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/helpers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multicall"
+)
+
+var errNoMulticall = errors.New("multicall is not configured")
+
+// ErrPairNotDeployed - set to state of the pair, which address was
+// computed, but factory has not created it yet
+var ErrPairNotDeployed = errors.New("pair is not deployed")
+
+// PairState - tokens and reserves of the pair, loaded at the same
+// block with other pairs of the batch. Err is set if any call for
+// the pair failed, e.g. if it is not a Uniswap V2 pair.
+type PairState struct {
+	Pair     *UniswapV2Pair
+	Token0   common.Address
+	Token1   common.Address
+	Reserve0 *big.Int
+	Reserve1 *big.Int
+	Err      error
+}
+
+// LoadPairs - requests tokens and reserves of pairs with multicall,
+// tokens are requested only if they are not known or cached. Returns
+// block number at which all pairs were loaded. State of the pair that
+// has no contract has ErrPairNotDeployed error.
+func (u *UniswapV2) LoadPairs(ctx context.Context, pairs []*UniswapV2Pair) (uint64, []PairState, error) {
+	if u.multicall == nil {
+		return 0, nil, errNoMulticall
+	}
+
+	pairABI, err := uniswapv2pair.UniswapV2PairMetaData.GetAbi()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to parse pair ABI")
+	}
+
+	// indexes of calls of the pair, token calls are -1 if
+	// tokens are already known
+	type pairCalls struct {
+		token0, token1, reserves int
+	}
+
+	calls := make([]multicall.Call, 0, 3*len(pairs))
+	indexes := make([]pairCalls, len(pairs))
+
+	for i, pair := range pairs {
+		indexes[i] = pairCalls{token0: -1, token1: -1}
+
+		if !pair.cachedTokens(ctx) {
+			indexes[i].token0 = len(calls)
+			calls = append(calls, packCall(pairABI, pair.Address, "token0"))
+			indexes[i].token1 = len(calls)
+			calls = append(calls, packCall(pairABI, pair.Address, "token1"))
+		}
+
+		indexes[i].reserves = len(calls)
+		calls = append(calls, packCall(pairABI, pair.Address, "getReserves"))
+	}
+
+	block, results, err := u.multicall.Call(ctx, 0, calls)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to load pairs")
+	}
+
+	states := make([]PairState, len(pairs))
+	for i, pair := range pairs {
+		states[i] = PairState{Pair: pair}
+
+		// call to address without code succeeds with empty data
+		if reserves := results[indexes[i].reserves]; reserves.Err == nil && len(reserves.Data) == 0 {
+			states[i].Err = ErrPairNotDeployed
+			continue
+		}
+
+		if indexes[i].token0 >= 0 {
+			token0, err := unpackAddress(pairABI, "token0", results[indexes[i].token0])
+			if err != nil {
+				states[i].Err = errors.Wrap(err, "failed to get token0")
+				continue
+			}
+
+			token1, err := unpackAddress(pairABI, "token1", results[indexes[i].token1])
+			if err != nil {
+				states[i].Err = errors.Wrap(err, "failed to get token1")
+				continue
+			}
+
+			pair.setTokens(ctx, token0, token1)
+		}
+
+		reserve0, reserve1, err := unpackReserves(pairABI, results[indexes[i].reserves])
+		if err != nil {
+			states[i].Err = errors.Wrap(err, "failed to get reserves")
+			continue
+		}
+
+		states[i].Token0 = pair.token0
+		states[i].Token1 = pair.token1
+		states[i].Reserve0 = reserve0
+		states[i].Reserve1 = reserve1
+	}
+
+	return block, states, nil
+}
+
+// AllPairsRange - returns pairs with indexes in [from, to), addresses
+// that are not cached are requested with multicall
+func (u *UniswapV2Factory) AllPairsRange(ctx context.Context, from, to uint64) ([]*UniswapV2Pair, error) {
+	if u.multicall == nil {
+		return nil, errNoMulticall
+	}
+
+	factoryABI, err := uniswapv2factory.UniswapV2FactoryMetaData.GetAbi()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse factory ABI")
+	}
+
+	addresses := make([]common.Address, to-from)
+	calls := make([]multicall.Call, 0, len(addresses))
+	// missing - indexes of addresses that are requested from node
+	missing := make([]uint64, 0, len(addresses))
+
+	for index := from; index < to; index++ {
+		if u.provider != nil {
+			pair, err := u.provider.GetPairByIndex(ctx, u.Address, index)
+			if err != nil {
+				u.logger.WithError(err).Error("failed to get pair from cache")
+			}
+			if !helpers.IsAddressZero(pair) {
+				addresses[index-from] = pair
+				continue
+			}
+		}
+
+		missing = append(missing, index)
+		calls = append(calls, packCall(factoryABI, u.Address, "allPairs", new(big.Int).SetUint64(index)))
+	}
+
+	if len(calls) != 0 {
+		_, results, err := u.multicall.Call(ctx, 0, calls)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get pairs addresses")
+		}
+
+		for i, index := range missing {
+			pair, err := unpackAddress(factoryABI, "allPairs", results[i])
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get pair address", logan.F{
+					"index": index,
+				})
+			}
+
+			addresses[index-from] = pair
+
+			if u.provider != nil {
+				err = u.provider.SetPairByIndex(ctx, u.Address, pair, index)
+				if err != nil {
+					u.logger.WithError(err).Error("failed to set pair to cache")
+				}
+			}
+		}
+	}
+
+	return u.newPairs(addresses)
+}
+
+// GetPools - returns pairs of tokens, pair has zero address if factory
+// has no pair for the tokens. If init code hash of factory is known,
+// addresses and tokens are computed offline without requests, and such
+// pair could be not deployed yet: LoadPairs confirms its existence in
+// the same batch that loads reserves. Otherwise addresses are requested
+// from factory.
+func (u *UniswapV2Factory) GetPools(ctx context.Context, tokens [][2]common.Address) ([]*UniswapV2Pair, error) {
+	addresses := make([]common.Address, len(tokens))
+	// computed - pairs which addresses are computed, and
+	// which tokens are known without requests
+	computed := make([]bool, len(tokens))
+	missing := make([]int, 0, len(tokens))
+
+	for i, pairTokens := range tokens {
+		if pair, ok := u.PairAddress(pairTokens[0], pairTokens[1]); ok {
+			addresses[i] = pair
+			computed[i] = true
+			continue
+		}
+
+		if u.provider != nil {
+			pair, err := u.provider.GetPairByTokens(ctx, u.Address, pairTokens[0], pairTokens[1])
+			if err != nil {
+				u.logger.WithError(err).Error("failed to get pair from cache")
+			}
+			if !helpers.IsAddressZero(pair) {
+				addresses[i] = pair
+				continue
+			}
+		}
+
+		missing = append(missing, i)
+	}
+
+	if len(missing) != 0 {
+		if err := u.requestPools(ctx, tokens, missing, addresses); err != nil {
+			return nil, err
+		}
+	}
+
+	pairs, err := u.newPairs(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pair := range pairs {
+		if computed[i] {
+			pair.token0, pair.token1 = SortTokens(tokens[i][0], tokens[i][1])
+		}
+	}
+
+	return pairs, nil
+}
+
+// requestPools - sets addresses of pairs with indexes from factory
+// with multicall, and caches ones that are created
+func (u *UniswapV2Factory) requestPools(
+	ctx context.Context, tokens [][2]common.Address, indexes []int, addresses []common.Address,
+) error {
+	if u.multicall == nil {
+		return errNoMulticall
+	}
+
+	factoryABI, err := uniswapv2factory.UniswapV2FactoryMetaData.GetAbi()
+	if err != nil {
+		return errors.Wrap(err, "failed to parse factory ABI")
+	}
+
+	calls := make([]multicall.Call, len(indexes))
+	for i, index := range indexes {
+		calls[i] = packCall(factoryABI, u.Address, "getPair", tokens[index][0], tokens[index][1])
+	}
+
+	_, results, err := u.multicall.Call(ctx, 0, calls)
+	if err != nil {
+		return errors.Wrap(err, "failed to get pairs addresses")
+	}
+
+	for i, index := range indexes {
+		pair, err := unpackAddress(factoryABI, "getPair", results[i])
+		if err != nil {
+			return errors.Wrap(err, "failed get pair from node", logan.F{
+				"token0": tokens[index][0].Hex(),
+				"token1": tokens[index][1].Hex(),
+			})
+		}
+
+		addresses[index] = pair
+
+		// pair that is not created yet is not cached,
+		// as it could be created later
+		if u.provider != nil && !helpers.IsAddressZero(pair) {
+			err = u.provider.SetPairByTokens(ctx, u.Address, tokens[index][0], tokens[index][1], pair)
+			if err != nil {
+				u.logger.WithError(err).Error("failed to set pair to cache")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (u *UniswapV2Factory) newPairs(addresses []common.Address) ([]*UniswapV2Pair, error) {
+	pairs := make([]*UniswapV2Pair, len(addresses))
+
+	for i, address := range addresses {
+		pair, err := u.NewPair(address, common.Address{}, common.Address{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create pair", logan.F{
+				"pair": address,
+			})
+		}
+
+		pairs[i] = pair
+	}
+
+	return pairs, nil
+}
+
+// packCall - packs call of the method, panics on invalid
+// arguments, as they are always known at compile time
+func packCall(contractABI *abi.ABI, target common.Address, method string, args ...interface{}) multicall.Call {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		panic(errors.Wrap(err, "failed to pack call", logan.F{
+			"method": method,
+		}))
+	}
+
+	return multicall.Call{
+		Target: target,
+		Data:   data,
+	}
+}
+
+func unpackAddress(contractABI *abi.ABI, method string, result multicall.Result) (common.Address, error) {
+	if result.Err != nil {
+		return common.Address{}, result.Err
+	}
+
+	out, err := contractABI.Unpack(method, result.Data)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "failed to unpack result")
+	}
+
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+func unpackReserves(pairABI *abi.ABI, result multicall.Result) (*big.Int, *big.Int, error) {
+	if result.Err != nil {
+		return nil, nil, result.Err
+	}
+
+	out, err := pairABI.Unpack("getReserves", result.Data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to unpack result")
+	}
+
+	reserve0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+	reserve1 := *abi.ConvertType(out[1], new(*big.Int)).(**big.Int)
+
+	return reserve0, reserve1, nil
+}
@@ -6,19 +6,36 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	uniswapv2factory "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-factory"
 	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
 	"github.com/Velnbur/uniswapv2-indexer/pkg/helpers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multicall"
 )
 
-type UniswapV2FactoryConfig struct {
+// UniswapV2FactoryParams - parameters of Uniswap V2 or one of its
+// forks (SushiSwap, etc.), that differ between venues
+type UniswapV2FactoryParams struct {
+	Name    string
 	Address common.Address
-	Client  *ethclient.Client
-	Logger  *logan.Entry
+	// Fee - swap fee of all pairs created by factory
+	Fee math.Fee
+	// InitCodeHash - keccak256 of pair creation code, that is
+	// used to compute pair address with CREATE2
+	InitCodeHash common.Hash
+}
+
+type UniswapV2FactoryConfig struct {
+	UniswapV2FactoryParams
+
+	Client bind.ContractBackend
+	Logger *logan.Entry
+	// Multicall - optional batcher of calls, that is
+	// required for batch requests of pairs
+	Multicall *multicall.Multicall
 
 	Provider      providers.UniswapV2FactoryProvider
 	PairProvider  providers.UniswapV2PairProvider
@@ -26,11 +43,13 @@ type UniswapV2FactoryConfig struct {
 }
 
 type UniswapV2Factory struct {
-	address  common.Address
+	UniswapV2FactoryParams
+
 	contract *uniswapv2factory.UniswapV2Factory
 
-	client *ethclient.Client
-	logger *logan.Entry
+	client    bind.ContractBackend
+	multicall *multicall.Multicall
+	logger    *logan.Entry
 
 	provider      providers.UniswapV2FactoryProvider
 	pairProvider  providers.UniswapV2PairProvider
@@ -47,17 +66,43 @@ func NewUniswapV2Factory(cfg UniswapV2FactoryConfig) (*UniswapV2Factory, error)
 			"factory_address": cfg.Address,
 		})
 	}
+
+	if cfg.Fee.IsZero() {
+		cfg.Fee = math.DefaultFee
+	}
+
 	return &UniswapV2Factory{
-		address:       cfg.Address,
-		client:        cfg.Client,
-		contract:      contract,
-		provider:      cfg.Provider,
-		pairProvider:  cfg.PairProvider,
-		logger:        cfg.Logger,
-		erc20Provider: cfg.Erc20Provider,
+		UniswapV2FactoryParams: cfg.UniswapV2FactoryParams,
+		client:                 cfg.Client,
+		multicall:              cfg.Multicall,
+		contract:               contract,
+		provider:               cfg.Provider,
+		pairProvider:           cfg.PairProvider,
+		logger:                 cfg.Logger,
+		erc20Provider:          cfg.Erc20Provider,
 	}, nil
 }
 
+// NewPair creates pair contract that uses the same client and providers
+// as factory. Tokens could be zero, then they are requested when needed.
+func (u *UniswapV2Factory) NewPair(
+	address, token0, token1 common.Address,
+) (*UniswapV2Pair, error) {
+	return NewUniswapV2Pair(
+		UniswapV2PairConfig{
+			Address:       address,
+			Factory:       u.Address,
+			Fee:           u.Fee,
+			Client:        u.client,
+			Logger:        u.logger,
+			Token0:        token0,
+			Token1:        token1,
+			Provider:      u.pairProvider,
+			Erc20Provider: u.erc20Provider,
+		},
+	)
+}
+
 // AllPairLength returns the number of all pairs
 func (u *UniswapV2Factory) AllPairLength(ctx context.Context) (uint64, error) {
 	// TODO: may be cache this too
@@ -65,31 +110,48 @@ func (u *UniswapV2Factory) AllPairLength(ctx context.Context) (uint64, error) {
 		Context: ctx,
 	})
 	if err != nil {
-		return 0, errors.New("failed to get all pairs length")
+		return 0, errors.Wrap(err, "failed to get all pairs length")
 	}
 	return length.Uint64(), nil
 }
 
+// DiscoveredPairs returns number of pairs, starting from index 0,
+// which addresses are already cached
+func (u *UniswapV2Factory) DiscoveredPairs(ctx context.Context) (uint64, error) {
+	if u.provider == nil {
+		return 0, nil
+	}
+
+	count, err := u.provider.GetDiscoveredPairs(ctx, u.Address)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get discovered pairs from cache")
+	}
+
+	return count, nil
+}
+
+// SetDiscoveredPairs saves number of pairs which addresses are cached
+func (u *UniswapV2Factory) SetDiscoveredPairs(ctx context.Context, count uint64) error {
+	if u.provider == nil {
+		return nil
+	}
+
+	err := u.provider.SetDiscoveredPairs(ctx, u.Address, count)
+	return errors.Wrap(err, "failed to set discovered pairs to cache")
+}
+
 // AllPairs return pair by index
 func (u *UniswapV2Factory) AllPairs(
 	ctx context.Context, index uint64,
 ) (*UniswapV2Pair, error) {
 	// first check cache
 	if u.provider != nil {
-		pair, err := u.provider.GetPairByIndex(ctx, u.address, index)
+		pair, err := u.provider.GetPairByIndex(ctx, u.Address, index)
 		if err != nil {
 			u.logger.WithError(err).Error("failed to get pair from cache")
 		}
 		if !helpers.IsAddressZero(pair) {
-			return NewUniswapV2Pair(
-				UniswapV2PairConfig{
-					Address:       pair,
-					Client:        u.client,
-					Logger:        u.logger,
-					Provider:      u.pairProvider,
-					Erc20Provider: u.erc20Provider,
-				},
-			)
+			return u.NewPair(pair, common.Address{}, common.Address{})
 		}
 	}
 
@@ -103,41 +165,34 @@ func (u *UniswapV2Factory) AllPairs(
 
 	// save to cache
 	if u.provider != nil {
-		err = u.provider.SetPairByIndex(ctx, u.address, pairAddress, index)
+		err = u.provider.SetPairByIndex(ctx, u.Address, pairAddress, index)
 		if err != nil {
 			u.logger.WithError(err).Error("failed to set pair to cache")
 		}
 	}
 
-	return NewUniswapV2Pair(
-		UniswapV2PairConfig{
-			Address:       pairAddress,
-			Client:        u.client,
-			Logger:        u.logger,
-			Provider:      u.pairProvider,
-			Erc20Provider: u.erc20Provider,
-		},
-	)
+	return u.NewPair(pairAddress, common.Address{}, common.Address{})
 }
 
+// GetPool - returns pair of tokens. Address and tokens are computed
+// offline if init code hash is known, then pair could be not deployed
+// yet, which LoadPairs reports. Otherwise address is requested from
+// factory, and it is zero if factory has no pair for the tokens.
 func (u *UniswapV2Factory) GetPool(
 	ctx context.Context, token0, token1 common.Address,
 ) (*UniswapV2Pair, error) {
+	if pair, ok := u.PairAddress(token0, token1); ok {
+		sorted0, sorted1 := SortTokens(token0, token1)
+		return u.NewPair(pair, sorted0, sorted1)
+	}
+
 	if u.provider != nil {
-		pair, err := u.provider.GetPairByTokens(ctx, u.address, token0, token1)
+		pair, err := u.provider.GetPairByTokens(ctx, u.Address, token0, token1)
 		if err != nil {
 			u.logger.WithError(err).Error("failed to get pair from cache")
 		}
 		if !helpers.IsAddressZero(pair) {
-			return NewUniswapV2Pair(
-				UniswapV2PairConfig{
-					Address:       pair,
-					Client:        u.client,
-					Logger:        u.logger,
-					Provider:      u.pairProvider,
-					Erc20Provider: u.erc20Provider,
-				},
-			)
+			return u.NewPair(pair, common.Address{}, common.Address{})
 		}
 	}
 
@@ -151,21 +206,20 @@ func (u *UniswapV2Factory) GetPool(
 		})
 	}
 
-	// save to cache
+	if helpers.IsAddressZero(pair) {
+		return u.NewPair(pair, common.Address{}, common.Address{})
+	}
+
+	// save to cache, pair that is not created yet is not
+	// cached, as it could be created later
 	if u.provider != nil {
-		err = u.provider.SetPairByTokens(ctx, u.address, token0, token1, pair)
+		err := u.provider.SetPairByTokens(ctx, u.Address, token0, token1, pair)
 		if err != nil {
 			u.logger.WithError(err).Error("failed to set pair to cache")
 		}
 	}
 
-	return NewUniswapV2Pair(
-		UniswapV2PairConfig{
-			Address:       pair,
-			Client:        u.client,
-			Logger:        u.logger,
-			Provider:      u.pairProvider,
-			Erc20Provider: u.erc20Provider,
-		},
-	)
+	sorted0, sorted1 := SortTokens(token0, token1)
+
+	return u.NewPair(pair, sorted0, sorted1)
 }
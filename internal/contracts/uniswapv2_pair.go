@@ -6,20 +6,29 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 	"gitlab.com/distributed_lab/logan/v3"
 
 	uniswapv2pair "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-pair"
 	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
 	"github.com/Velnbur/uniswapv2-indexer/pkg/helpers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 )
 
 type UniswapV2PairConfig struct {
 	Address common.Address
-	Client  *ethclient.Client
+	Client  bind.ContractBackend
 	Logger  *logan.Entry
 
+	// Token0, Token1 - optional tokens of the pair, if they are
+	// already known. Otherwise they are requested when needed.
+	Token0, Token1 common.Address
+
+	// Factory - factory that created the pair
+	Factory common.Address
+	// Fee - swap fee of the pair, default one is used if zero
+	Fee math.Fee
+
 	Provider      providers.UniswapV2PairProvider
 	Erc20Provider providers.Erc20Provider
 }
@@ -29,13 +38,15 @@ type UniswapV2Pair struct {
 	token1 common.Address
 
 	Address  common.Address
+	Factory  common.Address
+	Fee      math.Fee
 	contract *uniswapv2pair.UniswapV2Pair
 
 	provider      providers.UniswapV2PairProvider
 	erc20Provider providers.Erc20Provider
 	logger        *logan.Entry
 
-	client *ethclient.Client
+	client bind.ContractBackend
 }
 
 func NewUniswapV2Pair(cfg UniswapV2PairConfig) (*UniswapV2Pair, error) {
@@ -44,8 +55,16 @@ func NewUniswapV2Pair(cfg UniswapV2PairConfig) (*UniswapV2Pair, error) {
 		return nil, errors.Wrap(err, "failed to create uniswapv2pair contract")
 	}
 
+	if cfg.Fee.IsZero() {
+		cfg.Fee = math.DefaultFee
+	}
+
 	return &UniswapV2Pair{
+		token0:        cfg.Token0,
+		token1:        cfg.Token1,
 		Address:       cfg.Address,
+		Factory:       cfg.Factory,
+		Fee:           cfg.Fee,
 		contract:      contract,
 		provider:      cfg.Provider,
 		logger:        cfg.Logger,
@@ -108,18 +127,8 @@ func (u *UniswapV2Pair) Token1(ctx context.Context) (*ERC20, error) {
 }
 
 func (u *UniswapV2Pair) initTokens(ctx context.Context) error {
-	// get from cache first
-
-	if u.provider != nil {
-		token0, token1, err := u.provider.GetTokens(ctx, u.Address)
-		if err != nil {
-			u.logger.WithError(err).Error("failed to get tokens from provider")
-		}
-		if !helpers.IsAddressZero(token0) && !helpers.IsAddressZero(token1) {
-			u.token0 = token0
-			u.token1 = token1
-			return nil
-		}
+	if u.cachedTokens(ctx) {
+		return nil
 	}
 
 	token0, token1, err := u.getTokensFromContract(ctx)
@@ -127,16 +136,46 @@ func (u *UniswapV2Pair) initTokens(ctx context.Context) error {
 		return errors.Wrap(err, "failed to get tokens")
 	}
 
+	u.setTokens(ctx, token0, token1)
+
+	return nil
+}
+
+// cachedTokens - returns true if tokens are known,
+// taking them from cache if they are not set yet
+func (u *UniswapV2Pair) cachedTokens(ctx context.Context) bool {
+	if !helpers.IsAddressZero(u.token0) && !helpers.IsAddressZero(u.token1) {
+		return true
+	}
+
+	if u.provider == nil {
+		return false
+	}
+
+	token0, token1, err := u.provider.GetTokens(ctx, u.Address)
+	if err != nil {
+		u.logger.WithError(err).Error("failed to get tokens from provider")
+	}
+	if helpers.IsAddressZero(token0) || helpers.IsAddressZero(token1) {
+		return false
+	}
+
+	u.token0 = token0
+	u.token1 = token1
+
+	return true
+}
+
+// setTokens - sets tokens requested from node and saves them to cache
+func (u *UniswapV2Pair) setTokens(ctx context.Context, token0, token1 common.Address) {
 	u.token0 = token0
 	u.token1 = token1
 
 	if u.provider != nil {
-		if err = u.provider.SetTokens(ctx, u.Address, token0, token1); err != nil {
+		if err := u.provider.SetTokens(ctx, u.Address, token0, token1); err != nil {
 			u.logger.WithError(err).Error("failed to set tokens to provider")
 		}
 	}
-
-	return nil
 }
 
 func (u *UniswapV2Pair) getTokensFromContract(
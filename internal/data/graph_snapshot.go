@@ -0,0 +1,54 @@
+package data
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+)
+
+// GraphSnapshot - state of the tokens graph after the log with
+// Position was applied. Position with its block hash is a checkpoint,
+// that is saved together with the state, so logs up to it are applied
+// exactly once after restart.
+type GraphSnapshot struct {
+	Position LogPosition         `json:"position"`
+	Nodes    []GraphSnapshotNode `json:"nodes"`
+	Edges    []GraphSnapshotEdge `json:"edges"`
+	// Blocks - changes made by recent blocks, so they could be
+	// reverted if blocks are reorganized while service is down
+	Blocks []GraphSnapshotBlock `json:"blocks,omitempty"`
+}
+
+type GraphSnapshotNode struct {
+	Token  common.Address `json:"token"`
+	Symbol string         `json:"symbol,omitempty"`
+}
+
+type GraphSnapshotEdge struct {
+	Address  common.Address `json:"address"`
+	Factory  common.Address `json:"factory"`
+	Fee      math.Fee       `json:"fee"`
+	Token0   common.Address `json:"token0"`
+	Token1   common.Address `json:"token1"`
+	Reserve0 *big.Int       `json:"reserve0"`
+	Reserve1 *big.Int       `json:"reserve1"`
+	// Position - position of the log reserves were taken from
+	Position LogPosition `json:"position"`
+}
+
+type GraphSnapshotBlock struct {
+	Number  uint64                `json:"number"`
+	Hash    common.Hash           `json:"hash"`
+	Changes []GraphSnapshotChange `json:"changes"`
+}
+
+// GraphSnapshotChange - state of the pair before it was changed
+// in the block, reserves are nil if pair was created in it
+type GraphSnapshotChange struct {
+	Pair     common.Address `json:"pair"`
+	Reserve0 *big.Int       `json:"reserve0,omitempty"`
+	Reserve1 *big.Int       `json:"reserve1,omitempty"`
+	Position LogPosition    `json:"position"`
+}
@@ -4,18 +4,41 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-type Path []common.Address
+// Path - sequence of swaps, where Pairs[i] is the pair that
+// is used to swap Tokens[i] to Tokens[i+1]
+type Path struct {
+	Tokens []common.Address
+	Pairs  []common.Address
+}
 
 func NewPath(start common.Address) Path {
-	return Path{start}
+	return Path{
+		Tokens: []common.Address{start},
+		Pairs:  []common.Address{},
+	}
+}
+
+// Append - adds hop to the token through the pair
+func (p *Path) Append(pair, token common.Address) {
+	p.Pairs = append(p.Pairs, pair)
+	p.Tokens = append(p.Tokens, token)
+}
+
+// Hops - returns number of swaps in path
+func (p Path) Hops() int {
+	return len(p.Pairs)
+}
+
+func (p Path) First() common.Address {
+	return p.Tokens[0]
 }
 
-func (p Path) Append(addr common.Address) {
-	p = append(p, addr)
+func (p Path) Last() common.Address {
+	return p.Tokens[len(p.Tokens)-1]
 }
 
 func (p Path) Contains(addr common.Address) bool {
-	for _, elem := range p {
+	for _, elem := range p.Tokens {
 		if elem == addr {
 			return true
 		}
@@ -25,9 +48,73 @@ func (p Path) Contains(addr common.Address) bool {
 }
 
 func (p Path) Copy() Path {
-	path := make(Path, len(p))
+	path := Path{
+		Tokens: make([]common.Address, len(p.Tokens)),
+		Pairs:  make([]common.Address, len(p.Pairs)),
+	}
+
+	copy(path.Tokens, p.Tokens)
+	copy(path.Pairs, p.Pairs)
+
+	return path
+}
+
+func (p Path) Equal(other Path) bool {
+	if len(p.Tokens) != len(other.Tokens) || len(p.Pairs) != len(other.Pairs) {
+		return false
+	}
+
+	for i := range p.Tokens {
+		if p.Tokens[i] != other.Tokens[i] {
+			return false
+		}
+	}
+
+	for i := range p.Pairs {
+		if p.Pairs[i] != other.Pairs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reverse - returns new path in opposite direction
+func (p Path) Reverse() Path {
+	path := Path{
+		Tokens: make([]common.Address, len(p.Tokens)),
+		Pairs:  make([]common.Address, len(p.Pairs)),
+	}
+
+	for i, elem := range p.Tokens {
+		path.Tokens[len(p.Tokens)-1-i] = elem
+	}
+
+	for i, elem := range p.Pairs {
+		path.Pairs[len(p.Pairs)-1-i] = elem
+	}
+
+	return path
+}
+
+// Concat - returns new path that continues p with other, where
+// other starts with the last token of p
+func (p Path) Concat(other Path) Path {
+	path := p.Copy()
 
-	copy(path, p)
+	path.Tokens = append(path.Tokens, other.Tokens[1:]...)
+	path.Pairs = append(path.Pairs, other.Pairs...)
 
 	return path
 }
+
+// Intersects - returns true if paths have at least one common token
+func (p Path) Intersects(other Path) bool {
+	for _, elem := range other.Tokens {
+		if p.Contains(elem) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package data
+
+import "github.com/ethereum/go-ethereum/common"
+
+// LogPosition - position of the log in the chain, and transaction
+// context of it, that is attached to every event caused by log
+type LogPosition struct {
+	BlockNumber uint64 `json:"block_number"`
+	TxIndex     uint   `json:"tx_index"`
+	LogIndex    uint   `json:"log_index"`
+	// BlockHash - hash of the block of the log, that is not
+	// compared by After, but allows to check that position
+	// is still in canonical chain
+	BlockHash common.Hash `json:"block_hash"`
+	// TxHash - hash of the transaction that emitted the log
+	TxHash common.Hash `json:"tx_hash"`
+}
+
+// EndOfBlock - returns position that is after all logs of the block
+func EndOfBlock(block uint64) LogPosition {
+	return LogPosition{
+		BlockNumber: block,
+		TxIndex:     ^uint(0),
+		LogIndex:    ^uint(0),
+	}
+}
+
+// IsEndOfBlock - returns true if position is not of a log, but of
+// the state after the block, e.g. reserves requested from node
+func (p LogPosition) IsEndOfBlock() bool {
+	return p.TxIndex == ^uint(0) && p.LogIndex == ^uint(0)
+}
+
+// IsZero - returns true if position is not set
+func (p LogPosition) IsZero() bool {
+	return p == LogPosition{}
+}
+
+// After - returns true if log with position p was emitted after other
+func (p LogPosition) After(other LogPosition) bool {
+	if p.BlockNumber != other.BlockNumber {
+		return p.BlockNumber > other.BlockNumber
+	}
+
+	if p.TxIndex != other.TxIndex {
+		return p.TxIndex > other.TxIndex
+	}
+
+	return p.LogIndex > other.LogIndex
+}
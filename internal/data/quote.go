@@ -0,0 +1,121 @@
+package data
+
+import (
+	"math/big"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+)
+
+// Quote - result of swap simulation along the path
+type Quote struct {
+	Path Path
+	// Amounts - amount of tokens on every hop of the path,
+	// where Amounts[0] is amount in and the last one is amount out
+	Amounts []*big.Int
+	// MidPrices - spot prices of every hop before the swap, as amount
+	// of hop's output token per one input token
+	MidPrices []*big.Float
+}
+
+func (q *Quote) AmountIn() *big.Int {
+	return q.Amounts[0]
+}
+
+func (q *Quote) AmountOut() *big.Int {
+	return q.Amounts[len(q.Amounts)-1]
+}
+
+// MidPrice - returns spot price of the whole path, as amount of
+// output token per one input token
+func (q *Quote) MidPrice() *big.Float {
+	result := big.NewFloat(1)
+
+	for _, price := range q.MidPrices {
+		result.Mul(result, price)
+	}
+
+	return result
+}
+
+// ExecutionPrice - returns price the whole swap gets
+func (q *Quote) ExecutionPrice() *big.Float {
+	return math.ExecutionPrice(q.AmountIn(), q.AmountOut())
+}
+
+// PriceImpactBps - returns price impact of the whole swap in basis points
+func (q *Quote) PriceImpactBps() float64 {
+	return math.PriceImpactBps(q.MidPrice(), q.ExecutionPrice())
+}
+
+// HopPriceImpactBps - returns price impact of every hop in basis points
+func (q *Quote) HopPriceImpactBps() []float64 {
+	result := make([]float64, len(q.MidPrices))
+
+	for i, price := range q.MidPrices {
+		result[i] = math.PriceImpactBps(price, math.ExecutionPrice(q.Amounts[i], q.Amounts[i+1]))
+	}
+
+	return result
+}
+
+// MinAmountOut - returns minimal amount that will be received if price
+// moves against the swap by slippageBps basis points
+func (q *Quote) MinAmountOut(slippageBps uint64) *big.Int {
+	return math.MinAmountOut(q.AmountOut(), slippageBps)
+}
+
+// MaxAmountIn - returns maximal amount that should be sent to get amount
+// out if price moves against the swap by slippageBps basis points
+func (q *Quote) MaxAmountIn(slippageBps uint64) *big.Int {
+	return math.MaxAmountIn(q.AmountIn(), slippageBps)
+}
+
+// Split - distribution of amount in between several pathes
+type Split struct {
+	Quotes []*Quote
+}
+
+func (s *Split) AmountIn() *big.Int {
+	result := big.NewInt(0)
+
+	for _, quote := range s.Quotes {
+		result.Add(result, quote.AmountIn())
+	}
+
+	return result
+}
+
+func (s *Split) AmountOut() *big.Int {
+	result := big.NewInt(0)
+
+	for _, quote := range s.Quotes {
+		result.Add(result, quote.AmountOut())
+	}
+
+	return result
+}
+
+// ExecutionPrice - returns price the whole split gets
+func (s *Split) ExecutionPrice() *big.Float {
+	return math.ExecutionPrice(s.AmountIn(), s.AmountOut())
+}
+
+// PriceImpactBps - returns price impact of the split in basis points
+// relatively to the best mid price between its pathes
+func (s *Split) PriceImpactBps() float64 {
+	midPrice := new(big.Float)
+
+	for _, quote := range s.Quotes {
+		if price := quote.MidPrice(); price.Cmp(midPrice) > 0 {
+			midPrice = price
+		}
+	}
+
+	return math.PriceImpactBps(midPrice, s.ExecutionPrice())
+}
+
+// MinAmountOut - returns minimal amount that will be received if price
+// moves against the swap by slippageBps basis points
+func (s *Split) MinAmountOut(slippageBps uint64) *big.Int {
+	return math.MinAmountOut(s.AmountOut(), slippageBps)
+}
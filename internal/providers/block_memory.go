@@ -0,0 +1,28 @@
+package providers
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// BlockMemoryProvider - keeps block only in memory, e.g. for
+// replay, that should not affect state of running service
+type BlockMemoryProvider struct {
+	block uint64
+}
+
+// NewBlockMemoryProvider returns a new BlockMemoryProvider.
+func NewBlockMemoryProvider() *BlockMemoryProvider {
+	return &BlockMemoryProvider{}
+}
+
+// CurrentBlock returns the current block.
+func (p *BlockMemoryProvider) CurrentBlock(_ context.Context) (uint64, error) {
+	return atomic.LoadUint64(&p.block), nil
+}
+
+// UpdateBlock updates the current block.
+func (p *BlockMemoryProvider) UpdateBlock(_ context.Context, block uint64) error {
+	atomic.StoreUint64(&p.block, block)
+	return nil
+}
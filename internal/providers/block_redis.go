@@ -9,22 +9,36 @@ import (
 
 type BlockRedisProvider struct {
 	redis *redis.Client
+	key   string
 
 	block uint64
 }
 
+const (
+	currentBlockKey   = "current_block"
+	confirmedBlockKey = "confirmed_block"
+)
+
 // NewBlockProvider returns a new BlockProvider.
 func NewBlockProvider(redis *redis.Client) *BlockRedisProvider {
 	return &BlockRedisProvider{
 		redis: redis,
+		key:   currentBlockKey,
 	}
 }
 
-const currentBlockKey = "current_block"
+// NewConfirmedBlockProvider returns BlockProvider of the last block,
+// which events were sent after it was confirmed.
+func NewConfirmedBlockProvider(redis *redis.Client) *BlockRedisProvider {
+	return &BlockRedisProvider{
+		redis: redis,
+		key:   confirmedBlockKey,
+	}
+}
 
 // CurrentBlock returns the current block.
 func (p *BlockRedisProvider) CurrentBlock(ctx context.Context) (uint64, error) {
-	block, err := p.redis.Get(ctx, currentBlockKey).Uint64()
+	block, err := p.redis.Get(ctx, p.key).Uint64()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return 0, nil
@@ -39,7 +53,7 @@ func (p *BlockRedisProvider) CurrentBlock(ctx context.Context) (uint64, error) {
 func (p *BlockRedisProvider) UpdateBlock(ctx context.Context, block uint64) error {
 	if p.block != block {
 		p.block = block
-		return p.redis.Set(ctx, currentBlockKey, block, 0).Err()
+		return p.redis.Set(ctx, p.key, block, 0).Err()
 	}
 	return nil
 }
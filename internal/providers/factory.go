@@ -11,4 +11,8 @@ type UniswapV2FactoryProvider interface {
 	SetPairByIndex(ctx context.Context, factory, pair common.Address, index uint64) error
 	GetPairByTokens(ctx context.Context, factory, token0, token1 common.Address) (common.Address, error)
 	SetPairByTokens(ctx context.Context, factory, token0, token1, pair common.Address) error
+	// GetDiscoveredPairs - returns number of pairs, starting from index
+	// 0, which addresses are already cached by pairs discovery
+	GetDiscoveredPairs(ctx context.Context, factory common.Address) (uint64, error)
+	SetDiscoveredPairs(ctx context.Context, factory common.Address, count uint64) error
 }
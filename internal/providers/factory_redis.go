@@ -77,3 +77,29 @@ func (p *UniswapV2FactoryRedisProvider) SetPairByTokens(
 
 	return p.redis.Set(ctx, key, pair.Hex(), 0).Err()
 }
+
+const uniswapV2FactoryDiscoveredKey = "uniswapV2:factory:%s:discovered"
+
+func (p *UniswapV2FactoryRedisProvider) GetDiscoveredPairs(
+	ctx context.Context, factory common.Address,
+) (uint64, error) {
+	key := fmt.Sprintf(uniswapV2FactoryDiscoveredKey, factory.Hex())
+
+	count, err := p.redis.Get(ctx, key).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "failed to get discovered pairs")
+	}
+
+	return count, nil
+}
+
+func (p *UniswapV2FactoryRedisProvider) SetDiscoveredPairs(
+	ctx context.Context, factory common.Address, count uint64,
+) error {
+	key := fmt.Sprintf(uniswapV2FactoryDiscoveredKey, factory.Hex())
+
+	return p.redis.Set(ctx, key, count, 0).Err()
+}
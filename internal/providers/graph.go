@@ -0,0 +1,13 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+type GraphSnapshotProvider interface {
+	// GetSnapshot returns nil if there is no saved snapshot
+	GetSnapshot(ctx context.Context) (*data.GraphSnapshot, error)
+	SetSnapshot(ctx context.Context, snapshot *data.GraphSnapshot) error
+}
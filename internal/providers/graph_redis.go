@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+var _ GraphSnapshotProvider = &GraphSnapshotRedisProvider{}
+
+type GraphSnapshotRedisProvider struct {
+	redis *redis.Client
+}
+
+func NewGraphSnapshotRedisProvider(redis *redis.Client) *GraphSnapshotRedisProvider {
+	return &GraphSnapshotRedisProvider{
+		redis: redis,
+	}
+}
+
+const graphSnapshotKey = "graph:snapshot"
+
+func (p *GraphSnapshotRedisProvider) GetSnapshot(
+	ctx context.Context,
+) (*data.GraphSnapshot, error) {
+	raw, err := p.redis.Get(ctx, graphSnapshotKey).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get graph snapshot")
+	}
+
+	var snapshot data.GraphSnapshot
+	if err = json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal graph snapshot")
+	}
+
+	return &snapshot, nil
+}
+
+func (p *GraphSnapshotRedisProvider) SetSnapshot(
+	ctx context.Context, snapshot *data.GraphSnapshot,
+) error {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal graph snapshot")
+	}
+
+	return p.redis.Set(ctx, graphSnapshotKey, raw, 0).Err()
+}
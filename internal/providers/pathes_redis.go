@@ -53,13 +53,17 @@ func (p *PathesRedisProvider) parsePathes(raw string) []data.Path {
 
 	pathes := make([]data.Path, len(rawPathes))
 
+	// path is stored as token, pair, token, ..., pair, token
 	for i, rawPath := range rawPathes {
 		pathElements := strings.Split(rawPath, pathElementSeparator)
 
-		pathes[i] = make([]common.Address, len(pathElements))
+		pathes[i] = data.NewPath(common.HexToAddress(pathElements[0]))
 
-		for j, element := range pathElements {
-			pathes[i][j] = common.HexToAddress(element)
+		for j := 2; j < len(pathElements); j += 2 {
+			pathes[i].Append(
+				common.HexToAddress(pathElements[j-1]),
+				common.HexToAddress(pathElements[j]),
+			)
 		}
 	}
 
@@ -80,10 +84,11 @@ func (p *PathesRedisProvider) pathesToString(pathes []data.Path) string {
 	rawPathes := make([]string, len(pathes))
 
 	for i, path := range pathes {
-		rawPath := make([]string, len(path))
+		rawPath := make([]string, 0, len(path.Tokens)+len(path.Pairs))
 
-		for j, elem := range path {
-			rawPath[j] = elem.Hex()
+		rawPath = append(rawPath, path.First().Hex())
+		for j, pair := range path.Pairs {
+			rawPath = append(rawPath, pair.Hex(), path.Tokens[j+1].Hex())
 		}
 
 		rawPathes[i] = strings.Join(rawPath, pathElementSeparator)
@@ -0,0 +1,16 @@
+package providers
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+// QuotesProvider - quotes swaps with the latest known reserves of pairs
+type QuotesProvider interface {
+	BestPath(input, output common.Address, amountIn *big.Int) *data.Quote
+	BestPathExactOut(input, output common.Address, amountOut *big.Int) *data.Quote
+	BestSplit(input, output common.Address, amountIn *big.Int, maxPathes, parts int) *data.Split
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+var _ QuotesProvider = &QuotesMemoryProvider{}
+
+// QuotesMemoryProvider - quotes swaps with graph of indexer that runs
+// in the same process. Graph is set when indexer is created, so API
+// could be started before it, and every quote is nil until then.
+type QuotesMemoryProvider struct {
+	mux   sync.RWMutex
+	graph QuotesProvider
+}
+
+func NewQuotesMemoryProvider() *QuotesMemoryProvider {
+	return &QuotesMemoryProvider{}
+}
+
+// SetGraph - sets graph that quotes swaps
+func (p *QuotesMemoryProvider) SetGraph(graph QuotesProvider) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.graph = graph
+}
+
+func (p *QuotesMemoryProvider) getGraph() QuotesProvider {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	return p.graph
+}
+
+func (p *QuotesMemoryProvider) BestPath(input, output common.Address, amountIn *big.Int) *data.Quote {
+	graph := p.getGraph()
+	if graph == nil {
+		return nil
+	}
+
+	return graph.BestPath(input, output, amountIn)
+}
+
+func (p *QuotesMemoryProvider) BestPathExactOut(input, output common.Address, amountOut *big.Int) *data.Quote {
+	graph := p.getGraph()
+	if graph == nil {
+		return nil
+	}
+
+	return graph.BestPathExactOut(input, output, amountOut)
+}
+
+func (p *QuotesMemoryProvider) BestSplit(
+	input, output common.Address, amountIn *big.Int, maxPathes, parts int,
+) *data.Split {
+	graph := p.getGraph()
+	if graph == nil {
+		return nil
+	}
+
+	return graph.BestSplit(input, output, amountIn, maxPathes, parts)
+}
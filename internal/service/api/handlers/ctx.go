@@ -12,7 +12,8 @@ type ctxKey int
 
 const (
 	logCtxKey ctxKey = iota
-	pathesProviderKey
+	quotesProviderKey
+	pendingQuotesProviderKey
 )
 
 func CtxLog(entry *logan.Entry) func(context.Context) context.Context {
@@ -25,12 +26,24 @@ func Log(r *http.Request) *logan.Entry {
 	return r.Context().Value(logCtxKey).(*logan.Entry)
 }
 
-func CtxPathesProvider(entry providers.PathesProvider) func(context.Context) context.Context {
+func CtxQuotesProvider(entry providers.QuotesProvider) func(context.Context) context.Context {
 	return func(ctx context.Context) context.Context {
-		return context.WithValue(ctx, pathesProviderKey, entry)
+		return context.WithValue(ctx, quotesProviderKey, entry)
 	}
 }
 
-func PathesProvider(r *http.Request) providers.PathesProvider {
-	return r.Context().Value(pathesProviderKey).(providers.PathesProvider)
+func QuotesProvider(r *http.Request) providers.QuotesProvider {
+	return r.Context().Value(quotesProviderKey).(providers.QuotesProvider)
+}
+
+func CtxPendingQuotesProvider(entry providers.QuotesProvider) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, pendingQuotesProviderKey, entry)
+	}
+}
+
+// PendingQuotesProvider - returns nil if pending view is disabled
+func PendingQuotesProvider(r *http.Request) providers.QuotesProvider {
+	quotes, _ := r.Context().Value(pendingQuotesProviderKey).(providers.QuotesProvider)
+	return quotes
 }
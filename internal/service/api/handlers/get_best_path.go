@@ -3,14 +3,19 @@ package handlers
 import (
 	"net/http"
 
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"gitlab.com/distributed_lab/ape"
 	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/service/api/requests"
+	"github.com/Velnbur/uniswapv2-indexer/internal/service/api/responses"
 )
 
-// Algorithm
-
+// GetBestPath - quotes swap of exact amount in along the best path, or
+// split between several pathes if requested, or swap of exact amount
+// out. Not found is returned if there is no path with enough liquidity.
+// Pending view is used instead of confirmed one, if requested.
 func GetBestPath(w http.ResponseWriter, r *http.Request) {
 	req, err := requests.NewBestPathRequest(r)
 	if err != nil {
@@ -19,10 +24,41 @@ func GetBestPath(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = PathesProvider(r).GetPathes(r.Context(), req.TokenIn, req.TokenOut)
-	if err != nil {
-		Log(r).WithError(err).Error("failed to get pathes from provider")
-		ape.RenderErr(w, problems.InternalError())
-		return
+	quotes := QuotesProvider(r)
+	if req.Pending {
+		quotes = PendingQuotesProvider(r)
+		if quotes == nil {
+			ape.RenderErr(w, problems.BadRequest(validation.Errors{
+				"pending": errors.New("pending view is disabled"),
+			})...)
+			return
+		}
+	}
+
+	switch {
+	case req.ExactOut():
+		quote := quotes.BestPathExactOut(req.TokenIn, req.TokenOut, req.AmountOut)
+		if quote == nil {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		ape.Render(w, responses.NewExactOutResponse(quote, req.SlippageBps))
+	case req.Split > 1:
+		split := quotes.BestSplit(req.TokenIn, req.TokenOut, req.AmountIn, req.Split, 0)
+		if split == nil {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		ape.Render(w, responses.NewSplitResponse(split, req.SlippageBps))
+	default:
+		quote := quotes.BestPath(req.TokenIn, req.TokenOut, req.AmountIn)
+		if quote == nil {
+			ape.RenderErr(w, problems.NotFound())
+			return
+		}
+
+		ape.Render(w, responses.NewBestPathResponse(quote, req.SlippageBps))
 	}
 }
@@ -5,24 +5,44 @@ import (
 	"net/http"
 
 	"github.com/Velnbur/uniswapv2-indexer/pkg/helpers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/math"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 	"gitlab.com/distributed_lab/urlval"
 )
 
 type bestPathRequestUrlParams struct {
-	TokenIn  string `url:"token_in"`
-	TokenOut string `url:"token_out"`
-	AmountIn string `url:"amount_in"`
+	TokenIn   string `url:"token_in"`
+	TokenOut  string `url:"token_out"`
+	AmountIn  string `url:"amount_in"`
+	AmountOut string `url:"amount_out"`
+	// Split - maximum number of pathes amount in could be
+	// distributed between
+	Split uint `url:"split"`
+	// Slippage - tolerance in basis points used to calculate
+	// minimum received amount
+	Slippage *uint64 `url:"slippage"`
+	// Pending - if true, swap is quoted with reserves of events
+	// which blocks are not confirmed yet
+	Pending bool `url:"pending"`
 }
 
 func (p bestPathRequestUrlParams) Validate() error {
 	err := validation.Errors{
 		"token_in":  validation.Validate(&p.TokenIn, validation.By(isHexAddress)),
 		"token_out": validation.Validate(&p.TokenOut, validation.By(isHexAddress)),
-		"amount_in": validation.Validate(&p.AmountIn, validation.Length(0, 100)), // TODO:
+		"amount_in": validation.Validate(&p.AmountIn,
+			validation.Required.When(p.AmountOut == ""),
+			validation.Empty.When(p.AmountOut != "").Error("only one of amount_in and amount_out could be set"),
+			validation.Length(0, 100), // TODO:
+		),
+		"amount_out": validation.Validate(&p.AmountOut, validation.Length(0, 100)),
+		"split": validation.Validate(&p.Split,
+			validation.Empty.When(p.AmountOut != "").Error("split is supported only for amount_in"),
+			validation.Max(uint(maxSplitPathes)),
+		),
+		"slippage": validation.Validate(p.Slippage, validation.Max(uint64(math.BpsDenominator))),
 	}
 
 	return err.Filter()
@@ -31,17 +51,39 @@ func (p bestPathRequestUrlParams) Validate() error {
 type BestPathRequest struct {
 	TokenIn  common.Address
 	TokenOut common.Address
+	// AmountIn - exact amount of TokenIn to swap, nil if request
+	// is for exact output
 	AmountIn *big.Int
+	// AmountOut - exact amount of TokenOut to receive, nil if request
+	// is for exact input
+	AmountOut *big.Int
+	// Split - maximum number of pathes AmountIn could be distributed
+	// between, zero or one means that only the best path is used
+	Split int
+	// SlippageBps - price movement tolerance in basis points
+	SlippageBps uint64
+	// Pending - if true, swap is quoted with pending view
+	Pending bool
+}
+
+// DefaultSlippageBps - slippage tolerance that is used if request
+// doesn't specify one
+const DefaultSlippageBps = 50
+
+const maxSplitPathes = 8
+
+// ExactOut - returns true if request asks for the amount of TokenIn required
+// to receive AmountOut
+func (req BestPathRequest) ExactOut() bool {
+	return req.AmountOut != nil
 }
 
 func (req BestPathRequest) Validate() error {
 	errs := validation.Errors{
-		"token_in":  validation.Validate(&req.TokenIn, validation.NotIn(helpers.ZeroAddress)),
-		"token_out": validation.Validate(&req.TokenOut, validation.NotIn(helpers.ZeroAddress)),
-		"amount_in": validation.Validate(&req.AmountIn,
-			validation.Min(big.NewInt(1)),
-			validation.Max(math.MaxBig256),
-		),
+		"token_in":   validation.Validate(&req.TokenIn, validation.NotIn(helpers.ZeroAddress)),
+		"token_out":  validation.Validate(&req.TokenOut, validation.NotIn(helpers.ZeroAddress)),
+		"amount_in":  validation.Validate(req.AmountIn, validation.By(isAmount)),
+		"amount_out": validation.Validate(req.AmountOut, validation.By(isAmount)),
 	}
 
 	return errs.Filter()
@@ -58,15 +100,30 @@ func NewBestPathRequest(r *http.Request) (*BestPathRequest, error) {
 		return nil, errors.Wrap(err, "invalid parameters in url")
 	}
 
-	amountIn, ok := new(big.Int).SetString(params.AmountIn, 10)
-	if !ok {
-		return nil, errors.New("invalid amount_in")
-	}
-
 	req := &BestPathRequest{
 		TokenIn:  common.HexToAddress(params.TokenIn),
 		TokenOut: common.HexToAddress(params.TokenOut),
-		AmountIn: amountIn,
+		Split:    int(params.Split),
+		Pending:  params.Pending,
+	}
+
+	req.SlippageBps = DefaultSlippageBps
+	if params.Slippage != nil {
+		req.SlippageBps = *params.Slippage
+	}
+
+	if params.AmountOut != "" {
+		amountOut, ok := new(big.Int).SetString(params.AmountOut, 10)
+		if !ok {
+			return nil, errors.New("invalid amount_out")
+		}
+		req.AmountOut = amountOut
+	} else {
+		amountIn, ok := new(big.Int).SetString(params.AmountIn, 10)
+		if !ok {
+			return nil, errors.New("invalid amount_in")
+		}
+		req.AmountIn = amountIn
 	}
 
 	return req, req.Validate()
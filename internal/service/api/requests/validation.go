@@ -1,7 +1,10 @@
 package requests
 
 import (
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
@@ -17,3 +20,26 @@ func isHexAddress(value interface{}) error {
 
 	return nil
 }
+
+// isAmount - checks that amount of tokens fits into uint256
+// and is positive. Nil amount is considered as not set.
+func isAmount(value interface{}) error {
+	amount, ok := value.(*big.Int)
+	if !ok {
+		return errors.New("invalid amount type")
+	}
+
+	if amount == nil {
+		return nil
+	}
+
+	if amount.Sign() <= 0 {
+		return errors.New("must be positive")
+	}
+
+	if amount.Cmp(math.MaxBig256) > 0 {
+		return errors.New("must fit into uint256")
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package responses
+
+import (
+	"math/big"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+// pricePrecision - number of significant digits of rendered prices
+const pricePrecision = 18
+
+type BestPathResponse struct {
+	AmountIn       string  `json:"amount_in"`
+	AmountOut      string  `json:"amount_out"`
+	ExecutionPrice string  `json:"execution_price"`
+	PriceImpactBps float64 `json:"price_impact_bps"`
+	// MinAmountOut - minimum received with requested slippage,
+	// set only if amount in is exact
+	MinAmountOut string `json:"min_amount_out,omitempty"`
+	// MaxAmountIn - maximum sent with requested slippage,
+	// set only if amount out is exact
+	MaxAmountIn string `json:"max_amount_in,omitempty"`
+	// Pathes - the best path, or every path of the split with
+	// the part of amount in it was allocated
+	Pathes []PathResponse `json:"pathes"`
+}
+
+type PathResponse struct {
+	Tokens []string `json:"tokens"`
+	Pairs  []string `json:"pairs"`
+	// Amounts - amounts of tokens on every hop, the first
+	// one is amount in and the last one is amount out
+	Amounts           []string  `json:"amounts"`
+	MidPrice          string    `json:"mid_price"`
+	ExecutionPrice    string    `json:"execution_price"`
+	PriceImpactBps    float64   `json:"price_impact_bps"`
+	HopPriceImpactBps []float64 `json:"hop_price_impact_bps"`
+}
+
+// NewBestPathResponse - renders quote of exact amount in
+func NewBestPathResponse(quote *data.Quote, slippageBps uint64) BestPathResponse {
+	return BestPathResponse{
+		AmountIn:       quote.AmountIn().String(),
+		AmountOut:      quote.AmountOut().String(),
+		ExecutionPrice: formatPrice(quote.ExecutionPrice()),
+		PriceImpactBps: quote.PriceImpactBps(),
+		MinAmountOut:   quote.MinAmountOut(slippageBps).String(),
+		Pathes:         []PathResponse{newPathResponse(quote)},
+	}
+}
+
+// NewExactOutResponse - renders quote of exact amount out
+func NewExactOutResponse(quote *data.Quote, slippageBps uint64) BestPathResponse {
+	return BestPathResponse{
+		AmountIn:       quote.AmountIn().String(),
+		AmountOut:      quote.AmountOut().String(),
+		ExecutionPrice: formatPrice(quote.ExecutionPrice()),
+		PriceImpactBps: quote.PriceImpactBps(),
+		MaxAmountIn:    quote.MaxAmountIn(slippageBps).String(),
+		Pathes:         []PathResponse{newPathResponse(quote)},
+	}
+}
+
+// NewSplitResponse - renders exact amount in distributed between pathes
+func NewSplitResponse(split *data.Split, slippageBps uint64) BestPathResponse {
+	pathes := make([]PathResponse, len(split.Quotes))
+	for i, quote := range split.Quotes {
+		pathes[i] = newPathResponse(quote)
+	}
+
+	return BestPathResponse{
+		AmountIn:       split.AmountIn().String(),
+		AmountOut:      split.AmountOut().String(),
+		ExecutionPrice: formatPrice(split.ExecutionPrice()),
+		PriceImpactBps: split.PriceImpactBps(),
+		MinAmountOut:   split.MinAmountOut(slippageBps).String(),
+		Pathes:         pathes,
+	}
+}
+
+func newPathResponse(quote *data.Quote) PathResponse {
+	tokens := make([]string, len(quote.Path.Tokens))
+	for i, token := range quote.Path.Tokens {
+		tokens[i] = token.Hex()
+	}
+
+	pairs := make([]string, len(quote.Path.Pairs))
+	for i, pair := range quote.Path.Pairs {
+		pairs[i] = pair.Hex()
+	}
+
+	amounts := make([]string, len(quote.Amounts))
+	for i, amount := range quote.Amounts {
+		amounts[i] = amount.String()
+	}
+
+	return PathResponse{
+		Tokens:            tokens,
+		Pairs:             pairs,
+		Amounts:           amounts,
+		MidPrice:          formatPrice(quote.MidPrice()),
+		ExecutionPrice:    formatPrice(quote.ExecutionPrice()),
+		PriceImpactBps:    quote.PriceImpactBps(),
+		HopPriceImpactBps: quote.HopPriceImpactBps(),
+	}
+}
+
+func formatPrice(price *big.Float) string {
+	return price.Text('g', pricePrecision)
+}
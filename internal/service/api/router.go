@@ -5,21 +5,31 @@ import (
 	"gitlab.com/distributed_lab/ape"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
 	"github.com/Velnbur/uniswapv2-indexer/internal/service/api/handlers"
 )
 
 func newRouter(cfg config.Config) chi.Router {
 	r := chi.NewRouter()
 
+	// pending view is set only if it is enabled, so
+	// requests for it are rejected otherwise
+	var pending providers.QuotesProvider
+	if cfg.ConfirmationsCfg().Pending {
+		pending = cfg.PendingQuotesView()
+	}
+
 	r.Use(
 		ape.RecoverMiddleware(cfg.Log()),
 		ape.LoganMiddleware(cfg.Log()),
 		ape.CtxMiddleware(
 			handlers.CtxLog(cfg.Log()),
+			handlers.CtxQuotesProvider(cfg.QuotesView()),
+			handlers.CtxPendingQuotesProvider(pending),
 		),
 	)
 	r.Route("/", func(r chi.Router) {
-		// configure endpoints here
+		r.Get("/best_path", handlers.GetBestPath)
 	})
 
 	return r
@@ -0,0 +1,295 @@
+package indexer
+
+import (
+	"bytes"
+	stdmath "math"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+)
+
+// weightEpsilon - minimal improvement of distance that is considered
+// as relaxation, protects from float rounding errors
+const weightEpsilon = 1e-9
+
+// Arbitrage - profitable cycle of swaps that starts and ends
+// with the same token
+type Arbitrage struct {
+	// Quote - swap of the optimal amount in along the cycle,
+	// where first and last tokens of the path are the same
+	data.Quote
+}
+
+// Profit - returns expected profit in the start token
+func (a *Arbitrage) Profit() *big.Int {
+	return new(big.Int).Sub(a.AmountOut(), a.AmountIn())
+}
+
+// FindArbitrages - searches for negative cycles in the graph with
+// -log(price after fee) edge weights, and returns those of them that
+// are profitable with integer swap amounts.
+func (g *Graph) FindArbitrages() []*Arbitrage {
+	return g.ArbitrageSnapshot().FindArbitrages()
+}
+
+// ArbitrageSnapshot - copy of pairs with liquidity and weights of swaps
+// through them, that is searched for arbitrages without graph lock
+type ArbitrageSnapshot struct {
+	// tokens - tokens sorted by address, so search is deterministic
+	tokens []common.Address
+	// hops - swaps that could be made by selling the token
+	hops  map[common.Address][]weightedHop
+	edges map[common.Address]*Edge
+}
+
+// weightedHop - swap through the pair with its weight
+type weightedHop struct {
+	Pair     common.Address
+	TokenOut common.Address
+	Weight   float64
+}
+
+// ArbitrageSnapshot - copies pairs that have liquidity, so graph could
+// be updated while the copy is searched for arbitrages
+func (g *Graph) ArbitrageSnapshot() *ArbitrageSnapshot {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	s := &ArbitrageSnapshot{
+		tokens: make([]common.Address, 0, len(g.nodes)),
+		hops:   make(map[common.Address][]weightedHop, len(g.nodes)),
+		edges:  make(map[common.Address]*Edge, len(g.edges)),
+	}
+
+	for _, edge := range g.edges {
+		for _, tokenIn := range []common.Address{edge.Token0, edge.Token1} {
+			weight := edge.Weight(tokenIn)
+			if stdmath.IsInf(weight, 1) {
+				continue
+			}
+
+			// reserves are replaced on update, not changed
+			// in place, so copy of edge is not affected
+			if _, ok := s.edges[edge.Address]; !ok {
+				copied := *edge
+				s.edges[edge.Address] = &copied
+			}
+
+			s.hops[tokenIn] = append(s.hops[tokenIn], weightedHop{
+				Pair:     edge.Address,
+				TokenOut: edge.Other(tokenIn),
+				Weight:   weight,
+			})
+		}
+	}
+
+	for token := range s.hops {
+		s.tokens = append(s.tokens, token)
+	}
+
+	sort.Slice(s.tokens, func(i, j int) bool {
+		return bytes.Compare(s.tokens[i].Bytes(), s.tokens[j].Bytes()) < 0
+	})
+
+	return s
+}
+
+// FindArbitrages - returns profitable cycles of the snapshot
+func (s *ArbitrageSnapshot) FindArbitrages() []*Arbitrage {
+	arbitrages := make([]*Arbitrage, 0)
+
+	for _, cycle := range s.negativeCycles() {
+		arbitrage, ok := s.arbitrage(cycle)
+		if !ok {
+			continue
+		}
+
+		arbitrages = append(arbitrages, arbitrage)
+	}
+
+	return arbitrages
+}
+
+// negativeCycles - runs SPFA from virtual source that is connected to
+// every token. Search stops as soon as no distance could be improved,
+// and every token is relaxed at most len(tokens) times, after which its
+// predecessors contain a negative cycle. Tokens of found cycles are not
+// relaxed anymore, so search is bounded even if there are many of them.
+func (s *ArbitrageSnapshot) negativeCycles() []data.Path {
+	n := len(s.tokens)
+
+	var (
+		dist        = make(map[common.Address]float64, n)
+		pred        = make(map[common.Address]hop, n)
+		relaxations = make(map[common.Address]int, n)
+		queued      = make(map[common.Address]bool, n)
+		inCycle     = make(map[common.Address]bool)
+		queue       = make([]common.Address, 0, n)
+
+		cycles = make([]data.Path, 0)
+		seen   = make(map[string]struct{})
+	)
+
+	for _, token := range s.tokens {
+		dist[token] = 0
+		queued[token] = true
+		queue = append(queue, token)
+	}
+
+	for len(queue) > 0 {
+		tokenIn := queue[0]
+		queue = queue[1:]
+		queued[tokenIn] = false
+
+		if inCycle[tokenIn] {
+			continue
+		}
+
+		for _, h := range s.hops[tokenIn] {
+			if inCycle[h.TokenOut] || dist[tokenIn]+h.Weight >= dist[h.TokenOut]-weightEpsilon {
+				continue
+			}
+
+			dist[h.TokenOut] = dist[tokenIn] + h.Weight
+			pred[h.TokenOut] = hop{Token: tokenIn, Pair: h.Pair}
+			relaxations[h.TokenOut]++
+
+			if relaxations[h.TokenOut] < n {
+				if !queued[h.TokenOut] {
+					queued[h.TokenOut] = true
+					queue = append(queue, h.TokenOut)
+				}
+				continue
+			}
+
+			start, ok := cycleStart(h.TokenOut, pred, n)
+			if !ok {
+				continue
+			}
+
+			cycle := canonicalCycle(cycleFrom(start, pred))
+			for _, token := range cycle.Tokens {
+				inCycle[token] = true
+			}
+
+			key := cycleKey(cycle)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			cycles = append(cycles, cycle)
+		}
+	}
+
+	return cycles
+}
+
+// cycleStart - follows predecessors of the token and returns the first
+// token that is met twice. Returns false if predecessors end before.
+func cycleStart(token common.Address, pred map[common.Address]hop, n int) (common.Address, bool) {
+	visited := make(map[common.Address]bool, n)
+
+	for i := 0; i <= n; i++ {
+		if visited[token] {
+			return token, true
+		}
+		visited[token] = true
+
+		prev, ok := pred[token]
+		if !ok {
+			return common.Address{}, false
+		}
+		token = prev.Token
+	}
+
+	return common.Address{}, false
+}
+
+// hop - predecessor of the token in Bellman-Ford, pair through
+// which token was reached from the previous one
+type hop struct {
+	Token common.Address
+	Pair  common.Address
+}
+
+// cycleFrom - restores cycle in swap direction that contains token
+// by following predecessors
+func cycleFrom(token common.Address, pred map[common.Address]hop) data.Path {
+	backward := data.NewPath(token)
+
+	current := token
+	for {
+		prev := pred[current]
+		backward.Append(prev.Pair, prev.Token)
+
+		if prev.Token == token {
+			break
+		}
+		current = prev.Token
+	}
+
+	return backward.Reverse()
+}
+
+// canonicalCycle - rotates cycle so that it starts with the token
+// with the lowest address, so the same cycles found from different
+// tokens are equal
+func canonicalCycle(cycle data.Path) data.Path {
+	tokens := cycle.Tokens[:len(cycle.Tokens)-1]
+
+	start := 0
+	for i, token := range tokens {
+		if bytes.Compare(token.Bytes(), tokens[start].Bytes()) < 0 {
+			start = i
+		}
+	}
+
+	rotated := data.NewPath(tokens[start])
+	for i := 0; i < len(tokens); i++ {
+		j := (start + i) % len(tokens)
+		rotated.Append(cycle.Pairs[j], cycle.Tokens[j+1])
+	}
+
+	return rotated
+}
+
+func cycleKey(cycle data.Path) string {
+	key := make([]byte, 0, len(cycle.Pairs)*common.AddressLength)
+
+	// pairs are enough to distinguish cycles, as every
+	// pair defines tokens on both sides of the hop
+	for _, pair := range cycle.Pairs {
+		key = append(key, pair.Bytes()...)
+	}
+
+	return string(key)
+}
+
+// arbitrage - calculates optimal amount in and expected profit of
+// the cycle. Returns false if cycle is not profitable.
+func (s *ArbitrageSnapshot) arbitrage(cycle data.Path) (*Arbitrage, bool) {
+	reservesIn, reservesOut, fees, ok := pathReserves(s.edges, cycle)
+	if !ok {
+		return nil, false
+	}
+
+	amountIn := math.OptimalAmountIn(reservesIn, reservesOut, fees)
+	if amountIn.Sign() <= 0 {
+		return nil, false
+	}
+
+	arbitrage := &Arbitrage{
+		Quote: data.Quote{
+			Path:      cycle,
+			Amounts:   math.GetAmountsOut(reservesIn, reservesOut, fees, amountIn),
+			MidPrices: midPrices(reservesIn, reservesOut),
+		},
+	}
+
+	return arbitrage, arbitrage.Profit().Sign() > 0
+}
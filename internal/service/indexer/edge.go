@@ -1,9 +1,13 @@
 package indexer
 
 import (
+	stdmath "math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 )
 
 type EdgeKey struct {
@@ -13,16 +17,65 @@ type EdgeKey struct {
 type Edge struct {
 	EdgeKey
 
+	// Address - address of the pair contract
+	Address common.Address
+	// Factory - address of the factory that created the pair
+	Factory common.Address
+	// Fee - swap fee of the pair, that is defined by its factory
+	Fee math.Fee
+
 	Reserve0, Reserve1 *big.Int
+	// Position - position of the log reserves were taken from,
+	// zero if they were requested from node
+	Position data.LogPosition
 }
 
-func NewEdge(token0, token1 common.Address, reserve0, reserve1 *big.Int) *Edge {
+func NewEdge(address, token0, token1 common.Address, reserve0, reserve1 *big.Int) *Edge {
 	return &Edge{
 		EdgeKey: EdgeKey{
 			Token0: token0,
 			Token1: token1,
 		},
+		Address:  address,
+		Fee:      math.DefaultFee,
 		Reserve0: reserve0,
 		Reserve1: reserve1,
 	}
 }
+
+// Reserves - returns reserves of the pair oriented in the swap direction,
+// where tokenIn is the token that is sold to the pair.
+func (e *Edge) Reserves(tokenIn common.Address) (reserveIn, reserveOut *big.Int) {
+	if tokenIn == e.Token0 {
+		return e.Reserve0, e.Reserve1
+	}
+
+	return e.Reserve1, e.Reserve0
+}
+
+// Other - returns token that is bought from the pair when tokenIn is sold
+func (e *Edge) Other(tokenIn common.Address) common.Address {
+	if tokenIn == e.Token0 {
+		return e.Token1
+	}
+
+	return e.Token0
+}
+
+// Weight - returns -log(price after fee) of swap where tokenIn is sold to
+// the pair, so that sum of weights along the path is the negative log of
+// the path's marginal rate. Returns +Inf if pair has no liquidity.
+func (e *Edge) Weight(tokenIn common.Address) float64 {
+	reserveIn, reserveOut := e.Reserves(tokenIn)
+
+	if reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return stdmath.Inf(1)
+	}
+
+	price, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(reserveOut),
+		new(big.Float).SetInt(reserveIn),
+	).Float64()
+
+	return -stdmath.Log(price * e.Fee.Float64())
+}
@@ -3,6 +3,7 @@ package indexer
 import (
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/data"
 	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
@@ -13,126 +14,490 @@ type Graph struct {
 	mux sync.RWMutex
 
 	nodes map[common.Address]*Node
-	edges map[common.Address]map[common.Address]*Edge
+	// edges - all pairs by their address
+	edges map[common.Address]*Edge
+	// routes - pairs between two tokens, there could be several
+	// pools for the same tokens, so every one of them is a route
+	routes map[common.Address]map[common.Address][]*Edge
 
 	pathesMap *PathesMap
+	limits    IndexLimits
+
+	// journal - changes of reserves in recent blocks, that
+	// are reverted on chain reorganization
+	journal *Journal
+	// block - block which logs are applied to the graph
+	block uint64
 }
 
 func NewGraph() *Graph {
 	return &Graph{
-		// weights: make(map[EdgeKey]*big.Float),
-		edges:     make(map[common.Address]map[common.Address]*Edge),
+		edges:     make(map[common.Address]*Edge),
+		routes:    make(map[common.Address]map[common.Address][]*Edge),
 		nodes:     make(map[common.Address]*Node),
 		pathesMap: NewPathesMap(),
+		journal:   NewJournal(0),
 	}
 }
 
+// WithReorgDepth - enables journal of reserves changes for the last
+// depth blocks, so they could be reverted with Rollback
+func (g *Graph) WithReorgDepth(depth uint64) *Graph {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.journal = NewJournal(depth)
+
+	return g
+}
+
+// BeginBlock - sets block which logs are applied to the graph, all
+// following changes are recorded to the journal under it
+func (g *Graph) BeginBlock(block uint64, hash common.Hash) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.block = block
+	g.journal.Begin(block, hash)
+}
+
+// Rollback - reverts reserves changes made after the block. Pairs
+// created after the block are kept in the graph, but without liquidity,
+// as their addresses are deterministic and they will be created again
+// if canonical chain has them.
+func (g *Graph) Rollback(block uint64) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	for pair, entry := range g.journal.Revert(block) {
+		edge, ok := g.edges[pair]
+		if !ok {
+			continue
+		}
+
+		edge.Position = entry.Position
+
+		if entry.Reserve0 == nil {
+			edge.Reserve0, edge.Reserve1 = big.NewInt(0), big.NewInt(0)
+			continue
+		}
+
+		edge.Reserve0, edge.Reserve1 = entry.Reserve0, entry.Reserve1
+	}
+
+	g.block = block
+}
+
+// WithLimits - sets limits for pathes enumeration. Should be
+// called before edges are added, as already found pathes are kept.
+func (g *Graph) WithLimits(limits IndexLimits) *Graph {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.limits = limits
+	g.pathesMap.SetMaxPerPair(limits.MaxPathesPerPair)
+
+	return g
+}
+
+// AddEdge - adds pair with default fee to the graph, see AddPair
 func (g *Graph) AddEdge(
-	token0, token1 common.Address, reserve0, reserve1 *big.Int,
+	pair, token0, token1 common.Address, reserve0, reserve1 *big.Int,
 ) *Graph {
+	return g.AddPair(NewEdge(pair, token0, token1, reserve0, reserve1))
+}
+
+// AddPair - adds pair to the graph and indexes only those pathes that
+// go through it. If pair already exists, only reserves are updated, if
+// they are newer or were requested from node. Shorter pathes are found
+// first, so if time budget is exceeded, already found ones are stored.
+func (g *Graph) AddPair(edge *Edge) *Graph {
 	g.mux.Lock()
 	defer g.mux.Unlock()
 
-	edge := NewEdge(token0, token1, reserve0, reserve1)
+	if existing, ok := g.edges[edge.Address]; ok {
+		if !edge.Position.IsZero() && !edge.Position.After(existing.Position) {
+			return g
+		}
+
+		g.journal.Record(g.block, existing.Address, existing)
+
+		existing.Reserve0, existing.Reserve1 = edge.Reserve0, edge.Reserve1
+		if !edge.Position.IsZero() {
+			existing.Position = edge.Position
+		}
+		return g
+	}
+
+	// pair loaded from node has reserves of canonical chain at the
+	// time it was requested, so it has no earlier state to revert
+	// to, and logs that are applied again after reorg are older
+	if !edge.Position.IsEndOfBlock() {
+		g.journal.Record(g.block, edge.Address, nil)
+	}
+
+	if edge.Fee.IsZero() {
+		edge.Fee = math.DefaultFee
+	}
+
+	// pathes are found before edge is added, so walkers
+	// don't go through the new edge
+	deadline := g.limits.deadline(time.Now())
+	pathes := g.pathesThrough(edge.Address, edge.Token0, edge.Token1, deadline)
 
 	g.addEdge(edge)
 
-	node0 := NewNode(token0)
-	node1 := NewNode(token1)
+	node0 := NewNode(edge.Token0)
+	node1 := NewNode(edge.Token1)
 
 	g.addNodes(node0, node1)
 
+	for _, path := range pathes {
+		if g.limits.pathesExceeded(g.pathesMap.Len()) {
+			break
+		}
+		g.pathesMap.AddPaths(path)
+	}
+
 	return g
 }
 
+// pathesThrough - returns all pathes in both directions that will
+// be possible after adding pair between token0 and token1. Every such
+// path consists of path that ends in token0, the pair itself and path
+// that starts in token1 (or vice versa). Pathes found before deadline
+// are returned, the direct one is always among them.
+func (g *Graph) pathesThrough(pair, token0, token1 common.Address, deadline time.Time) []data.Path {
+	// the edge itself is a hop, so other parts have one hop less
+	maxHops := -1
+	if g.limits.MaxHops > 0 {
+		maxHops = g.limits.MaxHops - 1
+	}
+
+	prefixes := g.walk(token0, maxHops, deadline)
+	suffixes := g.walk(token1, maxHops, deadline)
+
+	pathes := make([]data.Path, 0)
+
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			if maxHops >= 0 && prefix.Hops()+suffix.Hops() > maxHops {
+				continue
+			}
+
+			if prefix.Intersects(suffix) {
+				continue
+			}
+
+			path := prefix.Reverse()
+			path.Append(pair, token1)
+			path = path.Concat(suffix)
+
+			pathes = append(pathes, path, path.Reverse())
+
+			if expired(deadline) {
+				return pathes
+			}
+		}
+	}
+
+	return pathes
+}
+
+// walk - returns all simple pathes from root with at most maxHops
+// hops, including path that consists only of root. Negative maxHops
+// means no limit. Walking stops when deadline is passed.
+func (g *Graph) walk(root common.Address, maxHops int, deadline time.Time) []data.Path {
+	var (
+		walkers = []*Walker{NewWalker(root)}
+		pathes  = []data.Path{data.NewPath(root)}
+	)
+
+	for hops := 0; len(walkers) > 0 && (maxHops < 0 || hops < maxHops); hops++ {
+		newWalkers := make([]*Walker, 0)
+
+		for _, walker := range walkers {
+			if expired(deadline) {
+				return pathes
+			}
+
+			_walkers := walker.Next(g.routes[walker.Current()])
+
+			for _, next := range _walkers {
+				pathes = append(pathes, next.GetPath())
+			}
+
+			newWalkers = append(newWalkers, _walkers...)
+		}
+
+		walkers = newWalkers
+	}
+
+	return pathes
+}
+
 func (g *Graph) addEdge(edge *Edge) {
-	if _, ok := g.edges[edge.Token0]; !ok {
-		g.edges[edge.Token0] = make(map[common.Address]*Edge)
+	if _, ok := g.routes[edge.Token0]; !ok {
+		g.routes[edge.Token0] = make(map[common.Address][]*Edge)
 	}
 
-	if _, ok := g.edges[edge.Token1]; !ok {
-		g.edges[edge.Token1] = make(map[common.Address]*Edge)
+	if _, ok := g.routes[edge.Token1]; !ok {
+		g.routes[edge.Token1] = make(map[common.Address][]*Edge)
 	}
 
-	g.edges[edge.Token0][edge.Token1] = edge
-	g.edges[edge.Token1][edge.Token0] = edge
+	g.edges[edge.Address] = edge
+	g.routes[edge.Token0][edge.Token1] = append(g.routes[edge.Token0][edge.Token1], edge)
+	g.routes[edge.Token1][edge.Token0] = append(g.routes[edge.Token1][edge.Token0], edge)
 }
 
 func (g *Graph) addNodes(nodes ...*Node) *Graph {
 	for _, node := range nodes {
+		// node could already have symbol, so keep it
+		if _, ok := g.nodes[node.Token]; ok {
+			continue
+		}
 		g.nodes[node.Token] = node
 	}
 
 	return g
 }
 
-func (g *Graph) Index() {
-	for node := range g.nodes {
-		walkers := []*Walker{
-			NewWalker(node),
-		}
-		pathes := make([]data.Path, 0)
+// Index - enumerates pathes between all tokens in the graph within
+// configured limits, and replaces old pathes with them. Shorter pathes
+// are found first, so if indexing was stopped by time or pathes budget,
+// already found ones are still stored and error is returned.
+func (g *Graph) Index() error {
+	pathesMap, err := g.index()
+
+	g.mux.Lock()
+	g.pathesMap = pathesMap
+	g.mux.Unlock()
+
+	return err
+}
 
-		for len(walkers) > 0 {
+func (g *Graph) index() (*PathesMap, error) {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	var (
+		pathesMap = NewPathesMap().SetMaxPerPair(g.limits.MaxPathesPerPair)
+		deadline  = g.limits.deadline(time.Now())
+	)
+
+	for root := range g.nodes {
+		var (
+			walkers = []*Walker{NewWalker(root)}
+			// number of tokens that have no more space for pathes from root
+			full = 0
+		)
+
+		for hops := 0; len(walkers) > 0 && !g.limits.hopsExceeded(hops); hops++ {
 			newWalkers := make([]*Walker, 0)
 
 			for _, walker := range walkers {
-				finished, _walkers := walker.Next(g.edges[walker.Current()])
+				if expired(deadline) {
+					return pathesMap, ErrIndexTimeout
+				}
 
-				newWalkers = append(newWalkers, _walkers...)
+				_walkers := walker.Next(g.routes[walker.Current()])
 
-				if finished {
-					path := walker.GetPath()
+				for _, next := range _walkers {
+					// walker still could lead to other tokens,
+					// so it is not dropped even if pair is full
+					newWalkers = append(newWalkers, next)
 
-					if len(path) > 1 {
-						pathes = append(pathes, path)
+					// pathes are found in order of hops number, so
+					// new one can't replace already stored
+					if pathesMap.Full(root, next.Current()) {
+						continue
+					}
+
+					pathesMap.AddPaths(next.GetPath())
+
+					if pathesMap.Full(root, next.Current()) {
+						full++
+					}
+
+					if g.limits.pathesExceeded(pathesMap.Len()) {
+						return pathesMap, ErrIndexPathesLimit
 					}
 				}
 			}
 
+			// there is no token left where new pathes
+			// could be stored, so stop walking from root
+			if full == len(g.nodes)-1 {
+				break
+			}
+
 			walkers = newWalkers
 		}
-
-		g.pathesMap.AddPaths(pathes...)
 	}
+
+	return pathesMap, nil
 }
 
-func (g *Graph) BestPath(input, ouput common.Address, amountIn *big.Int) (data.Path, *big.Int) {
-	var (
-		bestPath      = make(data.Path, 0)
-		bestAmountOut = big.NewInt(0)
-	)
+// Pathes - returns all indexed pathes by their first and last tokens,
+// that could be read while graph is updated
+func (g *Graph) Pathes() map[EdgeKey][]data.Path {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
 
-	pathes := g.pathesMap.GetPath(input, ouput)
+	return g.pathesMap.Copy()
+}
 
-	for _, path := range pathes {
-		reserves0 := make([]*big.Int, 0, len(path)-1)
-		reserves1 := make([]*big.Int, 0, len(path)-1)
+// BestPath - simulates swap of amountIn along every known path from input
+// to output token and returns the one with the highest output. Returns nil
+// if there is no path that gives non zero output.
+func (g *Graph) BestPath(input, output common.Address, amountIn *big.Int) *data.Quote {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	var best *data.Quote
+
+	for _, path := range g.pathesMap.GetPath(input, output) {
+		quote, ok := g.quote(path, amountIn)
+		if !ok {
+			continue
+		}
+
+		if best == nil || quote.AmountOut().Cmp(best.AmountOut()) > 0 {
+			best = quote
+		}
+	}
+
+	return best
+}
 
-		for i := 1; i < len(path); i++ {
-			edge := g.edges[input][ouput]
+// BestPathExactOut - calculates required amount in to get amountOut along
+// every known path from input to output token and returns the one with
+// the lowest amount in. Returns nil if no path can provide such output.
+func (g *Graph) BestPathExactOut(input, output common.Address, amountOut *big.Int) *data.Quote {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
 
-			reserves0 = append(reserves0, edge.Reserve0)
-			reserves1 = append(reserves1, edge.Reserve1)
+	var best *data.Quote
+
+	for _, path := range g.pathesMap.GetPath(input, output) {
+		quote, ok := g.quoteExactOut(path, amountOut)
+		if !ok {
+			continue
 		}
 
-		amountOut := math.SwapAmountOut(reserves0, reserves1, amountIn)
+		if best == nil || quote.AmountIn().Cmp(best.AmountIn()) < 0 {
+			best = quote
+		}
+	}
+
+	return best
+}
+
+// quote - calculates amounts on every hop of the path. Returns false
+// if some of the hops are unknown or have no liquidity.
+func (g *Graph) quote(path data.Path, amountIn *big.Int) (*data.Quote, bool) {
+	reservesIn, reservesOut, fees, ok := g.reserves(path)
+	if !ok {
+		return nil, false
+	}
+
+	amounts := math.GetAmountsOut(reservesIn, reservesOut, fees, amountIn)
+
+	quote := &data.Quote{
+		Path:      path,
+		Amounts:   amounts,
+		MidPrices: midPrices(reservesIn, reservesOut),
+	}
+
+	return quote, quote.AmountOut().Sign() > 0
+}
+
+// quoteExactOut - calculates amounts on every hop of the path required
+// to get amountOut. Returns false if some of the hops are unknown or
+// have not enough liquidity.
+func (g *Graph) quoteExactOut(path data.Path, amountOut *big.Int) (*data.Quote, bool) {
+	reservesIn, reservesOut, fees, ok := g.reserves(path)
+	if !ok {
+		return nil, false
+	}
+
+	amounts := math.GetAmountsIn(reservesIn, reservesOut, fees, amountOut)
+	if amounts == nil {
+		return nil, false
+	}
+
+	return &data.Quote{
+		Path:      path,
+		Amounts:   amounts,
+		MidPrices: midPrices(reservesIn, reservesOut),
+	}, true
+}
+
+// reserves - returns reserves of every hop of the path oriented
+// in the swap direction and fees of the hops.
+func (g *Graph) reserves(path data.Path) (reservesIn, reservesOut []*big.Int, fees []math.Fee, ok bool) {
+	return pathReserves(g.edges, path)
+}
 
-		if bestAmountOut.Cmp(amountOut) > 0 {
-			bestAmountOut = amountOut
+// pathReserves - returns reserves and fees of pairs of the path
+// oriented in swap direction. Returns false if some pair is unknown.
+func pathReserves(
+	edges map[common.Address]*Edge, path data.Path,
+) (reservesIn, reservesOut []*big.Int, fees []math.Fee, ok bool) {
+	reservesIn = make([]*big.Int, 0, path.Hops())
+	reservesOut = make([]*big.Int, 0, path.Hops())
+	fees = make([]math.Fee, 0, path.Hops())
+
+	for i, pair := range path.Pairs {
+		edge, ok := edges[pair]
+		if !ok {
+			return nil, nil, nil, false
 		}
+
+		reserveIn, reserveOut := edge.Reserves(path.Tokens[i])
+
+		reservesIn = append(reservesIn, reserveIn)
+		reservesOut = append(reservesOut, reserveOut)
+		fees = append(fees, edge.Fee)
+	}
+
+	return reservesIn, reservesOut, fees, true
+}
+
+// midPrices - returns spot prices of every hop
+func midPrices(reservesIn, reservesOut []*big.Int) []*big.Float {
+	prices := make([]*big.Float, len(reservesIn))
+
+	for i := range reservesIn {
+		prices[i] = math.MidPrice(reservesIn[i], reservesOut[i])
 	}
 
-	return bestPath, bestAmountOut
+	return prices
 }
 
-func (g *Graph) UpdateReserves(
-	token0, token1 common.Address, reserve0Delta, reserve1Delta *big.Int,
-) {
+// SetReserves - sets reserves of the pair from the log with position,
+// if it is newer than the one reserves were taken from. Returns false
+// if pair is unknown or update is stale.
+func (g *Graph) SetReserves(
+	pair common.Address, reserve0, reserve1 *big.Int, position data.LogPosition,
+) bool {
 	g.mux.Lock()
 	defer g.mux.Unlock()
 
-	edge := g.edges[token0][token1]
-	edge.Reserve0.Add(edge.Reserve0, reserve0Delta)
-	edge.Reserve1.Add(edge.Reserve1, reserve1Delta)
+	edge, ok := g.edges[pair]
+	if !ok {
+		return false
+	}
+
+	if !position.After(edge.Position) {
+		return false
+	}
+
+	g.journal.Record(g.block, edge.Address, edge)
+
+	edge.Reserve0 = new(big.Int).Set(reserve0)
+	edge.Reserve1 = new(big.Int).Set(reserve1)
+	edge.Position = position
+
+	return true
 }
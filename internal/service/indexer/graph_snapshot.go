@@ -0,0 +1,86 @@
+package indexer
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+// Snapshot - returns copy of graph nodes and edges, sorted so snapshots
+// of equal graphs are equal too. Position of the snapshot should be set
+// by the caller.
+func (g *Graph) Snapshot() *data.GraphSnapshot {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	snapshot := &data.GraphSnapshot{
+		Nodes: make([]data.GraphSnapshotNode, 0, len(g.nodes)),
+		Edges: make([]data.GraphSnapshotEdge, 0),
+	}
+
+	for _, node := range g.nodes {
+		snapshot.Nodes = append(snapshot.Nodes, data.GraphSnapshotNode{
+			Token:  node.Token,
+			Symbol: node.Symbol,
+		})
+	}
+
+	for _, edge := range g.edges {
+		snapshot.Edges = append(snapshot.Edges, data.GraphSnapshotEdge{
+			Address:  edge.Address,
+			Factory:  edge.Factory,
+			Fee:      edge.Fee,
+			Token0:   edge.Token0,
+			Token1:   edge.Token1,
+			Reserve0: new(big.Int).Set(edge.Reserve0),
+			Reserve1: new(big.Int).Set(edge.Reserve1),
+			Position: edge.Position,
+		})
+	}
+
+	sort.Slice(snapshot.Nodes, func(i, j int) bool {
+		return bytes.Compare(snapshot.Nodes[i].Token.Bytes(), snapshot.Nodes[j].Token.Bytes()) < 0
+	})
+	sort.Slice(snapshot.Edges, func(i, j int) bool {
+		return bytes.Compare(snapshot.Edges[i].Address.Bytes(), snapshot.Edges[j].Address.Bytes()) < 0
+	})
+
+	snapshot.Blocks = g.journal.Snapshot()
+
+	return snapshot
+}
+
+// Restore - adds nodes and edges from snapshot to the graph, and
+// changes of recent blocks to the journal, so they could be reverted
+func (g *Graph) Restore(snapshot *data.GraphSnapshot) *Graph {
+	for _, edge := range snapshot.Edges {
+		pair := NewEdge(edge.Address, edge.Token0, edge.Token1, edge.Reserve0, edge.Reserve1)
+		pair.Factory = edge.Factory
+		pair.Position = edge.Position
+		// snapshots made before fees were stored have zero
+		// fee, that is replaced with default one
+		pair.Fee = edge.Fee
+
+		g.AddPair(pair)
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	for _, node := range snapshot.Nodes {
+		if _, ok := g.nodes[node.Token]; !ok {
+			g.nodes[node.Token] = NewNode(node.Token)
+		}
+		g.nodes[node.Token].AddSymbol(node.Symbol)
+	}
+
+	g.journal.Restore(snapshot.Blocks)
+	// the rest of logs of the snapshot block are
+	// recorded to the journal under it
+	g.block = snapshot.Position.BlockNumber
+	g.journal.Begin(snapshot.Position.BlockNumber, snapshot.Position.BlockHash)
+
+	return g
+}
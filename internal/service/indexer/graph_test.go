@@ -3,20 +3,36 @@ package indexer
 import (
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
 )
 
-func Test_GraphIndex(t *testing.T) {
+// testPair - returns fake, but deterministic pair address for tokens
+func testPair(token0, token1 common.Address) common.Address {
+	return common.BytesToAddress(crypto.Keccak256(token0.Bytes(), token1.Bytes()))
+}
 
-	tokens := []common.Address{
-		common.HexToAddress("0x0000000000000000000000000000000000000001"),
-		common.HexToAddress("0x0000000000000000000000000000000000000002"),
-		common.HexToAddress("0x0000000000000000000000000000000000000003"),
-		common.HexToAddress("0x0000000000000000000000000000000000000004"),
-		common.HexToAddress("0x0000000000000000000000000000000000000005"),
-	}
+// logAt - returns position of log in the first transaction of block
+func logAt(block uint64, index uint) data.LogPosition {
+	return data.LogPosition{BlockNumber: block, LogIndex: index}
+}
 
+var completeGraphTokens = []common.Address{
+	common.HexToAddress("0x0000000000000000000000000000000000000001"),
+	common.HexToAddress("0x0000000000000000000000000000000000000002"),
+	common.HexToAddress("0x0000000000000000000000000000000000000003"),
+	common.HexToAddress("0x0000000000000000000000000000000000000004"),
+	common.HexToAddress("0x0000000000000000000000000000000000000005"),
+}
+
+func newCompleteGraph() *Graph {
+	tokens := completeGraphTokens
 	graph := NewGraph()
 
 	// Graph:
@@ -26,16 +42,563 @@ func Test_GraphIndex(t *testing.T) {
 	// 1 1 1 0 1
 	// 1 1 1 1 0
 	graph.
-		AddEdge(tokens[0], tokens[1], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[0], tokens[2], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[0], tokens[3], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[0], tokens[4], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[1], tokens[2], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[1], tokens[3], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[1], tokens[4], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[2], tokens[3], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[2], tokens[4], big.NewInt(0), big.NewInt(0)).
-		AddEdge(tokens[3], tokens[4], big.NewInt(0), big.NewInt(0))
-
-	graph.Index()
+		AddEdge(testPair(tokens[0], tokens[1]), tokens[0], tokens[1], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[0], tokens[2]), tokens[0], tokens[2], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[0], tokens[3]), tokens[0], tokens[3], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[0], tokens[4]), tokens[0], tokens[4], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[1], tokens[2]), tokens[1], tokens[2], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[1], tokens[3]), tokens[1], tokens[3], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[1], tokens[4]), tokens[1], tokens[4], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[2], tokens[3]), tokens[2], tokens[3], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[2], tokens[4]), tokens[2], tokens[4], big.NewInt(0), big.NewInt(0)).
+		AddEdge(testPair(tokens[3], tokens[4]), tokens[3], tokens[4], big.NewInt(0), big.NewInt(0))
+
+	return graph
+}
+
+func Test_GraphIndex(t *testing.T) {
+	tokens := completeGraphTokens
+
+	graph := newCompleteGraph()
+	require.NoError(t, graph.Index())
+
+	// 1 direct path, 3 paths through one token, 6 through two and 6 through three
+	require.Len(t, graph.pathesMap.GetPath(tokens[0], tokens[1]), 16)
+	require.Len(t, graph.pathesMap.GetPath(tokens[1], tokens[0]), 16)
+}
+
+func Test_GraphIndexLimits(t *testing.T) {
+	tokens := completeGraphTokens
+
+	t.Run("max hops", func(t *testing.T) {
+		graph := newCompleteGraph().WithLimits(IndexLimits{MaxHops: 2})
+		require.NoError(t, graph.Index())
+
+		// 1 direct path and 3 pathes through one token
+		for _, path := range graph.pathesMap.GetPath(tokens[0], tokens[1]) {
+			require.LessOrEqual(t, path.Hops(), 2)
+		}
+		require.Len(t, graph.pathesMap.GetPath(tokens[0], tokens[1]), 4)
+	})
+
+	t.Run("max pathes per pair", func(t *testing.T) {
+		graph := newCompleteGraph().WithLimits(IndexLimits{MaxPathesPerPair: 2})
+		require.NoError(t, graph.Index())
+
+		pathes := graph.pathesMap.GetPath(tokens[0], tokens[1])
+		require.Len(t, pathes, 2)
+		// shortest pathes are preferred
+		require.Equal(t, 1, pathes[0].Hops())
+		require.Equal(t, 2, pathes[1].Hops())
+	})
+
+	t.Run("max pathes", func(t *testing.T) {
+		graph := newCompleteGraph().WithLimits(IndexLimits{MaxPathes: 5})
+		require.ErrorIs(t, graph.Index(), ErrIndexPathesLimit)
+
+		var total int
+		for _, pathes := range graph.Pathes() {
+			total += len(pathes)
+		}
+		require.Equal(t, 5, total)
+	})
+
+	t.Run("reindexing replaces pathes", func(t *testing.T) {
+		graph := newCompleteGraph()
+		require.NoError(t, graph.Index())
+		require.NoError(t, graph.Index())
+
+		require.Len(t, graph.pathesMap.GetPath(tokens[0], tokens[1]), 16)
+	})
+}
+
+func Test_GraphAddEdgeIncremental(t *testing.T) {
+	tokens := completeGraphTokens
+
+	for _, limits := range []IndexLimits{{}, {MaxHops: 2}, {MaxHops: 3}} {
+		incremental := NewGraph().WithLimits(limits)
+		indexed := NewGraph().WithLimits(limits)
+
+		for i := range tokens {
+			for j := i + 1; j < len(tokens); j++ {
+				incremental.AddEdge(testPair(tokens[i], tokens[j]), tokens[i], tokens[j], big.NewInt(0), big.NewInt(0))
+				indexed.AddEdge(testPair(tokens[i], tokens[j]), tokens[i], tokens[j], big.NewInt(0), big.NewInt(0))
+			}
+		}
+		require.NoError(t, indexed.Index())
+
+		for _, token0 := range tokens {
+			for _, token1 := range tokens {
+				require.ElementsMatch(t,
+					indexed.pathesMap.GetPath(token0, token1),
+					incremental.pathesMap.GetPath(token0, token1),
+				)
+			}
+		}
+		require.Equal(t, indexed.pathesMap.Len(), incremental.pathesMap.Len())
+	}
+
+	t.Run("time budget keeps direct pathes", func(t *testing.T) {
+		unlimited := newCompleteGraph()
+
+		graph := NewGraph().WithLimits(IndexLimits{Timeout: time.Nanosecond})
+		for i := range tokens {
+			for j := i + 1; j < len(tokens); j++ {
+				graph.AddEdge(testPair(tokens[i], tokens[j]), tokens[i], tokens[j], big.NewInt(0), big.NewInt(0))
+			}
+		}
+
+		for i := range tokens {
+			for j := i + 1; j < len(tokens); j++ {
+				require.NotEmpty(t, graph.pathesMap.GetPath(tokens[i], tokens[j]))
+				require.NotEmpty(t, graph.pathesMap.GetPath(tokens[j], tokens[i]))
+			}
+		}
+		require.Less(t, graph.pathesMap.Len(), unlimited.pathesMap.Len())
+	})
+
+	t.Run("existing edge only updates reserves", func(t *testing.T) {
+		graph := newCompleteGraph()
+		total := graph.pathesMap.Len()
+
+		graph.AddEdge(testPair(tokens[0], tokens[1]), tokens[0], tokens[1], big.NewInt(10), big.NewInt(20))
+
+		require.Equal(t, total, graph.pathesMap.Len())
+		require.Equal(t, big.NewInt(10), graph.edges[testPair(tokens[0], tokens[1])].Reserve0)
+		require.Equal(t, big.NewInt(20), graph.edges[testPair(tokens[0], tokens[1])].Reserve1)
+	})
+}
+
+func Test_GraphBestPath(t *testing.T) {
+	var (
+		weth = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		usdc = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		dai  = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	)
+
+	graph := NewGraph().
+		// shallow direct pool
+		AddEdge(testPair(weth, usdc), weth, usdc, big.NewInt(1_000), big.NewInt(1_000_000)).
+		// deep pools through dai, note that dai is token0 in the second pair
+		AddEdge(testPair(weth, dai), weth, dai, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+		AddEdge(testPair(dai, usdc), dai, usdc, big.NewInt(1_000_000_000), big.NewInt(1_000_000_000))
+
+	require.NoError(t, graph.Index())
+
+	t.Run("best path is chosen", func(t *testing.T) {
+		quote := graph.BestPath(weth, usdc, big.NewInt(100))
+		require.NotNil(t, quote)
+
+		require.Equal(t, []common.Address{weth, dai, usdc}, quote.Path.Tokens)
+		require.Equal(t, []*big.Int{
+			big.NewInt(100), big.NewInt(99_690), big.NewInt(99_381),
+		}, quote.Amounts)
+	})
+
+	t.Run("reserves are oriented", func(t *testing.T) {
+		quote := graph.BestPath(usdc, weth, big.NewInt(1_000_000))
+		require.NotNil(t, quote)
+
+		require.Equal(t, []common.Address{usdc, dai, weth}, quote.Path.Tokens)
+		require.Equal(t, big.NewInt(992), quote.AmountOut())
+	})
+
+	t.Run("liquidity runs out", func(t *testing.T) {
+		// both pathes are exhausted after a few parts
+		graph := NewGraph().
+			AddEdge(testPair(weth, usdc), weth, usdc, big.NewInt(1_000), big.NewInt(100)).
+			AddEdge(testPair(weth, dai), weth, dai, big.NewInt(1_000), big.NewInt(100)).
+			AddEdge(testPair(dai, usdc), dai, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000))
+
+		amountIn := big.NewInt(20_000)
+
+		split := graph.BestSplit(weth, usdc, amountIn, 2, 0)
+		require.NotNil(t, split)
+		require.Equal(t, amountIn, split.AmountIn())
+
+		best := graph.BestPath(weth, usdc, amountIn)
+		require.True(t, split.AmountOut().Cmp(best.AmountOut()) >= 0)
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		unknown := common.HexToAddress("0x0000000000000000000000000000000000000004")
+
+		require.Nil(t, graph.BestPath(weth, unknown, big.NewInt(100)))
+	})
+
+	t.Run("prices", func(t *testing.T) {
+		quote := graph.BestPath(weth, usdc, big.NewInt(100))
+		require.NotNil(t, quote)
+
+		midPrice, _ := quote.MidPrice().Float64()
+		require.InDelta(t, 1000, midPrice, 1e-9)
+
+		executionPrice, _ := quote.ExecutionPrice().Float64()
+		require.InDelta(t, 993.81, executionPrice, 1e-9)
+
+		require.InDelta(t, 61.9, quote.PriceImpactBps(), 1e-9)
+		require.Len(t, quote.HopPriceImpactBps(), 2)
+		require.Equal(t, big.NewInt(98_884), quote.MinAmountOut(50))
+	})
+
+	t.Run("exact output", func(t *testing.T) {
+		quote := graph.BestPathExactOut(weth, usdc, big.NewInt(99_381))
+		require.NotNil(t, quote)
+
+		require.Equal(t, []common.Address{weth, dai, usdc}, quote.Path.Tokens)
+		require.Equal(t, big.NewInt(100), quote.AmountIn())
+		require.Equal(t, big.NewInt(99_381), quote.AmountOut())
+	})
+
+	t.Run("exact output exceeds liquidity", func(t *testing.T) {
+		require.Nil(t, graph.BestPathExactOut(usdc, weth, big.NewInt(1_000_000)))
+	})
+}
+
+func Test_GraphParallelPools(t *testing.T) {
+	var (
+		weth = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		usdc = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		dai  = common.HexToAddress("0x0000000000000000000000000000000000000003")
+
+		shallow = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+		deep    = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	)
+
+	graph := NewGraph().
+		AddEdge(shallow, weth, usdc, big.NewInt(1_000), big.NewInt(1_000_000)).
+		AddEdge(deep, weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+		AddEdge(testPair(usdc, dai), usdc, dai, big.NewInt(1_000_000_000), big.NewInt(1_000_000_000))
+
+	t.Run("every pool is a path", func(t *testing.T) {
+		require.Len(t, graph.pathesMap.GetPath(weth, usdc), 2)
+		require.Len(t, graph.pathesMap.GetPath(weth, dai), 2)
+	})
+
+	t.Run("deeper pool is chosen", func(t *testing.T) {
+		quote := graph.BestPath(weth, usdc, big.NewInt(100))
+		require.NotNil(t, quote)
+
+		require.Equal(t, []common.Address{deep}, quote.Path.Pairs)
+	})
+
+	t.Run("reserves are updated by pair", func(t *testing.T) {
+		require.True(t, graph.SetReserves(shallow, big.NewInt(1_001), big.NewInt(999_001), logAt(1, 0)))
+
+		require.Equal(t, big.NewInt(1_001), graph.edges[shallow].Reserve0)
+		require.Equal(t, big.NewInt(1_000_000), graph.edges[deep].Reserve0)
+	})
+
+	t.Run("stale reserves are ignored", func(t *testing.T) {
+		require.False(t, graph.SetReserves(shallow, big.NewInt(1), big.NewInt(1), logAt(1, 0)))
+		require.False(t, graph.SetReserves(shallow, big.NewInt(1), big.NewInt(1), logAt(0, 5)))
+
+		require.Equal(t, big.NewInt(1_001), graph.edges[shallow].Reserve0)
+		require.Equal(t, logAt(1, 0), graph.edges[shallow].Position)
+
+		stale := NewEdge(shallow, weth, usdc, big.NewInt(1), big.NewInt(1))
+		stale.Position = logAt(0, 5)
+		graph.AddPair(stale)
+
+		require.Equal(t, big.NewInt(1_001), graph.edges[shallow].Reserve0)
+
+		// reserves requested from node are always the latest
+		graph.AddEdge(shallow, weth, usdc, big.NewInt(1_000), big.NewInt(1_000_000))
+		require.Equal(t, big.NewInt(1_000), graph.edges[shallow].Reserve0)
+	})
+
+	t.Run("pool fee is applied", func(t *testing.T) {
+		cheap := NewEdge(shallow, weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000))
+		cheap.Fee = math.Fee{Numerator: 9990, Denominator: 10000}
+
+		graph := NewGraph().
+			AddPair(cheap).
+			AddEdge(deep, weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000))
+
+		quote := graph.BestPath(weth, usdc, big.NewInt(100))
+		require.NotNil(t, quote)
+
+		require.Equal(t, []common.Address{shallow}, quote.Path.Pairs)
+		require.Equal(t, big.NewInt(99_890), quote.AmountOut())
+	})
+
+	t.Run("arbitrage between pools", func(t *testing.T) {
+		graph := NewGraph().
+			AddEdge(shallow, weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+			// weth is cheaper in the second pool
+			AddEdge(deep, weth, usdc, big.NewInt(1_000_000), big.NewInt(900_000_000))
+
+		arbitrages := graph.FindArbitrages()
+		require.Len(t, arbitrages, 1)
+
+		arbitrage := arbitrages[0]
+		require.Equal(t, []common.Address{weth, usdc, weth}, arbitrage.Path.Tokens)
+		require.Equal(t, []common.Address{shallow, deep}, arbitrage.Path.Pairs)
+		require.Positive(t, arbitrage.Profit().Sign())
+	})
+}
+
+func Test_GraphFindArbitrages(t *testing.T) {
+	var (
+		tokenA = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		tokenB = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		tokenC = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	)
+
+	t.Run("no arbitrage", func(t *testing.T) {
+		graph := NewGraph().
+			AddEdge(testPair(tokenA, tokenB), tokenA, tokenB, big.NewInt(1_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenB, tokenC), tokenB, tokenC, big.NewInt(2_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenC, tokenA), tokenC, tokenA, big.NewInt(2_000_000), big.NewInt(1_000_000))
+
+		require.Empty(t, graph.FindArbitrages())
+	})
+
+	t.Run("triangle", func(t *testing.T) {
+		graph := NewGraph().
+			AddEdge(testPair(tokenA, tokenB), tokenA, tokenB, big.NewInt(1_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenB, tokenC), tokenB, tokenC, big.NewInt(2_000_000), big.NewInt(2_000_000)).
+			// token A is cheaper than in the first pair
+			AddEdge(testPair(tokenC, tokenA), tokenC, tokenA, big.NewInt(1_000_000), big.NewInt(1_000_000))
+
+		arbitrages := graph.FindArbitrages()
+		require.Len(t, arbitrages, 1)
+
+		arbitrage := arbitrages[0]
+		require.Equal(t, []common.Address{tokenA, tokenB, tokenC, tokenA}, arbitrage.Path.Tokens)
+		require.Equal(t, []common.Address{
+			testPair(tokenA, tokenB), testPair(tokenB, tokenC), testPair(tokenC, tokenA),
+		}, arbitrage.Path.Pairs)
+		require.Positive(t, arbitrage.Profit().Sign())
+
+		// optimal amount gives more than slightly different ones
+		for _, delta := range []int64{-1000, 1000} {
+			amountIn := new(big.Int).Add(arbitrage.AmountIn(), big.NewInt(delta))
+			quote, ok := graph.quote(arbitrage.Path, amountIn)
+			require.True(t, ok)
+			require.LessOrEqual(t, new(big.Int).Sub(quote.AmountOut(), amountIn).Cmp(arbitrage.Profit()), 0)
+		}
+	})
+
+	t.Run("disjoint cycles", func(t *testing.T) {
+		var (
+			tokenD = common.HexToAddress("0x0000000000000000000000000000000000000004")
+			tokenE = common.HexToAddress("0x0000000000000000000000000000000000000005")
+		)
+
+		graph := NewGraph().
+			AddEdge(testPair(tokenA, tokenB), tokenA, tokenB, big.NewInt(1_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenB, tokenC), tokenB, tokenC, big.NewInt(2_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenC, tokenA), tokenC, tokenA, big.NewInt(1_000_000), big.NewInt(1_000_000)).
+			// two pools of the same tokens with different prices
+			AddEdge(testPair(tokenD, tokenE), tokenD, tokenE, big.NewInt(1_000_000), big.NewInt(1_000_000)).
+			AddEdge(testPair(tokenE, tokenD), tokenD, tokenE, big.NewInt(1_000_000), big.NewInt(900_000))
+
+		arbitrages := graph.FindArbitrages()
+		require.Len(t, arbitrages, 2)
+
+		starts := []common.Address{arbitrages[0].Path.First(), arbitrages[1].Path.First()}
+		require.ElementsMatch(t, []common.Address{tokenA, tokenD}, starts)
+	})
+
+	t.Run("snapshot is not changed by graph updates", func(t *testing.T) {
+		graph := NewGraph().
+			AddEdge(testPair(tokenA, tokenB), tokenA, tokenB, big.NewInt(1_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenB, tokenC), tokenB, tokenC, big.NewInt(2_000_000), big.NewInt(2_000_000)).
+			AddEdge(testPair(tokenC, tokenA), tokenC, tokenA, big.NewInt(1_000_000), big.NewInt(1_000_000))
+
+		snapshot := graph.ArbitrageSnapshot()
+
+		// arbitrage is closed after snapshot was taken
+		graph.SetReserves(testPair(tokenC, tokenA), big.NewInt(2_000_000), big.NewInt(1_000_000), logAt(1, 0))
+		require.Empty(t, graph.FindArbitrages())
+
+		require.Len(t, snapshot.FindArbitrages(), 1)
+	})
+}
+
+func Test_GraphBestSplit(t *testing.T) {
+	var (
+		weth = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		usdc = common.HexToAddress("0x0000000000000000000000000000000000000002")
+		dai  = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	)
+
+	graph := NewGraph().
+		AddEdge(testPair(weth, usdc), weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+		AddEdge(testPair(weth, dai), weth, dai, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+		AddEdge(testPair(dai, usdc), dai, usdc, big.NewInt(1_000_000_000), big.NewInt(1_000_000_000))
+
+	amountIn := big.NewInt(500_000)
+
+	t.Run("split is better than single path", func(t *testing.T) {
+		split := graph.BestSplit(weth, usdc, amountIn, 2, 0)
+		require.NotNil(t, split)
+		require.Len(t, split.Quotes, 2)
+		require.Equal(t, amountIn, split.AmountIn())
+
+		best := graph.BestPath(weth, usdc, amountIn)
+		require.Equal(t, 1, split.AmountOut().Cmp(best.AmountOut()))
+	})
+
+	t.Run("one path", func(t *testing.T) {
+		split := graph.BestSplit(weth, usdc, amountIn, 1, 0)
+		require.NotNil(t, split)
+		require.Len(t, split.Quotes, 1)
+
+		best := graph.BestPath(weth, usdc, amountIn)
+		require.Equal(t, best.Path, split.Quotes[0].Path)
+	})
+
+	t.Run("shared pairs", func(t *testing.T) {
+		// both pathes to dai go through weth/usdc pair
+		graph := NewGraph().
+			AddEdge(testPair(weth, usdc), weth, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000_000)).
+			AddEdge(testPair(usdc, dai), usdc, dai, big.NewInt(1_000_000_000), big.NewInt(1_000_000_000))
+
+		split := graph.BestSplit(weth, dai, amountIn, 2, 0)
+		require.NotNil(t, split)
+
+		best := graph.BestPath(weth, dai, amountIn)
+		require.Equal(t, best.AmountOut(), split.AmountOut())
+	})
+
+	t.Run("liquidity runs out", func(t *testing.T) {
+		// both pathes are exhausted after a few parts
+		graph := NewGraph().
+			AddEdge(testPair(weth, usdc), weth, usdc, big.NewInt(1_000), big.NewInt(100)).
+			AddEdge(testPair(weth, dai), weth, dai, big.NewInt(1_000), big.NewInt(100)).
+			AddEdge(testPair(dai, usdc), dai, usdc, big.NewInt(1_000_000), big.NewInt(1_000_000))
+
+		amountIn := big.NewInt(20_000)
+
+		split := graph.BestSplit(weth, usdc, amountIn, 2, 0)
+		require.NotNil(t, split)
+		require.Equal(t, amountIn, split.AmountIn())
+
+		best := graph.BestPath(weth, usdc, amountIn)
+		require.True(t, split.AmountOut().Cmp(best.AmountOut()) >= 0)
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		unknown := common.HexToAddress("0x0000000000000000000000000000000000000004")
+
+		require.Nil(t, graph.BestSplit(weth, unknown, amountIn, 2, 0))
+	})
+}
+
+func Test_GraphSnapshot(t *testing.T) {
+	tokens := completeGraphTokens
+
+	graph := NewGraph().
+		AddEdge(testPair(tokens[0], tokens[1]), tokens[0], tokens[1], big.NewInt(1_000), big.NewInt(2_000)).
+		AddEdge(testPair(tokens[1], tokens[2]), tokens[1], tokens[2], big.NewInt(3_000), big.NewInt(4_000))
+	graph.nodes[tokens[0]].AddSymbol("WETH")
+
+	snapshot := graph.Snapshot()
+	require.Len(t, snapshot.Nodes, 3)
+	require.Len(t, snapshot.Edges, 2)
+
+	// snapshot is not affected by further updates
+	graph.SetReserves(testPair(tokens[0], tokens[1]), big.NewInt(1), big.NewInt(1), logAt(1, 0))
+
+	restored := NewGraph().Restore(snapshot)
+	require.Equal(t, "WETH", restored.nodes[tokens[0]].Symbol)
+	require.Equal(t, big.NewInt(1_000), restored.edges[testPair(tokens[0], tokens[1])].Reserve0)
+	require.Equal(t, tokens[1], restored.edges[testPair(tokens[1], tokens[2])].Token0)
+	require.ElementsMatch(t,
+		graph.pathesMap.GetPath(tokens[0], tokens[2]),
+		restored.pathesMap.GetPath(tokens[0], tokens[2]),
+	)
+}
+
+func Test_GraphRollback(t *testing.T) {
+	var (
+		tokens = completeGraphTokens
+		pair01 = testPair(tokens[0], tokens[1])
+		pair12 = testPair(tokens[1], tokens[2])
+	)
+
+	graph := NewGraph().WithReorgDepth(4)
+
+	graph.BeginBlock(1, common.Hash{})
+	graph.AddEdge(pair01, tokens[0], tokens[1], big.NewInt(1_000), big.NewInt(2_000))
+
+	graph.BeginBlock(2, common.Hash{})
+	graph.SetReserves(pair01, big.NewInt(1_010), big.NewInt(1_990), logAt(2, 0))
+	graph.SetReserves(pair01, big.NewInt(1_020), big.NewInt(1_980), logAt(2, 1))
+
+	graph.BeginBlock(3, common.Hash{})
+	graph.SetReserves(pair01, big.NewInt(1_120), big.NewInt(1_880), logAt(3, 0))
+	graph.AddEdge(pair12, tokens[1], tokens[2], big.NewInt(3_000), big.NewInt(4_000))
+
+	t.Run("changes after block are reverted", func(t *testing.T) {
+		graph.Rollback(2)
+
+		require.Equal(t, big.NewInt(1_020), graph.edges[pair01].Reserve0)
+		require.Equal(t, big.NewInt(1_980), graph.edges[pair01].Reserve1)
+		require.Equal(t, logAt(2, 1), graph.edges[pair01].Position)
+
+		// pair created after block has no liquidity
+		require.Zero(t, graph.edges[pair12].Reserve0.Sign())
+		require.Zero(t, graph.edges[pair12].Reserve1.Sign())
+	})
+
+	t.Run("canonical logs are applied again", func(t *testing.T) {
+		graph.BeginBlock(3, common.Hash{})
+		graph.AddEdge(pair12, tokens[1], tokens[2], big.NewInt(5_000), big.NewInt(6_000))
+
+		graph.Rollback(1)
+
+		require.Equal(t, big.NewInt(1_000), graph.edges[pair01].Reserve0)
+		require.Zero(t, graph.edges[pair12].Reserve0.Sign())
+	})
+
+	t.Run("blocks deeper than depth are forgotten", func(t *testing.T) {
+		graph.BeginBlock(10, common.Hash{})
+		graph.SetReserves(pair01, big.NewInt(1_001), big.NewInt(2_001), logAt(10, 0))
+
+		graph.BeginBlock(20, common.Hash{})
+		graph.SetReserves(pair01, big.NewInt(1_002), big.NewInt(2_002), logAt(20, 0))
+
+		// only changes of block 20 are still known
+		graph.Rollback(1)
+
+		require.Equal(t, big.NewInt(1_001), graph.edges[pair01].Reserve0)
+	})
+
+	t.Run("changes are reverted after restore from snapshot", func(t *testing.T) {
+		graph.BeginBlock(21, common.Hash{})
+		graph.SetReserves(pair01, big.NewInt(1_003), big.NewInt(2_003), logAt(21, 0))
+
+		snapshot := graph.Snapshot()
+		snapshot.Position = logAt(21, 0)
+
+		restored := NewGraph().WithReorgDepth(4).Restore(snapshot)
+		restored.Rollback(20)
+
+		require.Equal(t, big.NewInt(1_001), restored.edges[pair01].Reserve0)
+		require.Equal(t, logAt(10, 0), restored.edges[pair01].Position)
+	})
+
+	t.Run("pairs loaded from node are not reverted", func(t *testing.T) {
+		pair23 := testPair(tokens[2], tokens[3])
+
+		snapshot := graph.Snapshot()
+		snapshot.Position = logAt(21, 0)
+
+		restored := NewGraph().WithReorgDepth(4).Restore(snapshot)
+
+		edge := NewEdge(pair23, tokens[2], tokens[3], big.NewInt(7_000), big.NewInt(8_000))
+		edge.Position = data.EndOfBlock(25)
+		restored.AddPair(edge)
+
+		restored.Rollback(20)
+
+		require.Equal(t, big.NewInt(7_000), restored.edges[pair23].Reserve0)
+		require.Equal(t, data.EndOfBlock(25), restored.edges[pair23].Position)
+
+		// logs of blocks before the one pair was loaded at are stale
+		require.False(t, restored.SetReserves(pair23, big.NewInt(1), big.NewInt(1), logAt(22, 0)))
+	})
 }
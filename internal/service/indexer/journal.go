@@ -0,0 +1,199 @@
+package indexer
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+// JournalEntry - state of the pair before it was changed
+type JournalEntry struct {
+	// Reserve0, Reserve1 - nil if pair was created in the block
+	Reserve0, Reserve1 *big.Int
+	Position           data.LogPosition
+}
+
+// Journal - reserves of pairs before they were changed in recent
+// blocks, that are used to revert changes made by reorganized blocks
+type Journal struct {
+	// depth - number of recent blocks that are kept, zero
+	// means that journal is disabled
+	depth uint64
+	// blocks - reserves of every pair before the first
+	// change in the block, by block number
+	blocks map[uint64]map[common.Address]JournalEntry
+	// hashes - hashes of blocks with changes
+	hashes map[uint64]common.Hash
+	last   uint64
+}
+
+func NewJournal(depth uint64) *Journal {
+	return &Journal{
+		depth:  depth,
+		blocks: make(map[uint64]map[common.Address]JournalEntry),
+		hashes: make(map[uint64]common.Hash),
+	}
+}
+
+// Begin - sets hash of the block, which changes are recorded next
+func (j *Journal) Begin(block uint64, hash common.Hash) {
+	if j.depth == 0 || block == 0 {
+		return
+	}
+
+	j.hashes[block] = hash
+}
+
+// Record - saves state of the edge if it is the first change of
+// the pair in the block. Nil edge means that pair is created.
+func (j *Journal) Record(block uint64, pair common.Address, edge *Edge) {
+	if j.depth == 0 || block == 0 {
+		return
+	}
+
+	changes, ok := j.blocks[block]
+	if !ok {
+		changes = make(map[common.Address]JournalEntry)
+		j.blocks[block] = changes
+	}
+
+	if _, ok := changes[pair]; ok {
+		return
+	}
+
+	var entry JournalEntry
+	if edge != nil {
+		entry = JournalEntry{
+			Reserve0: new(big.Int).Set(edge.Reserve0),
+			Reserve1: new(big.Int).Set(edge.Reserve1),
+			Position: edge.Position,
+		}
+	}
+
+	changes[pair] = entry
+
+	if block > j.last {
+		j.last = block
+		j.prune()
+	}
+}
+
+// Revert - returns state of pairs at the end of the block, for pairs
+// that were changed after it, and forgets those changes. Reserves are
+// nil for pairs that were created after the block.
+func (j *Journal) Revert(block uint64) map[common.Address]JournalEntry {
+	numbers := make([]uint64, 0)
+	for number := range j.blocks {
+		if number > block {
+			numbers = append(numbers, number)
+		}
+	}
+
+	// the earliest change after block has reserves that were
+	// actual at the end of the block, so it is applied last
+	sort.Slice(numbers, func(a, b int) bool {
+		return numbers[a] > numbers[b]
+	})
+
+	reverted := make(map[common.Address]JournalEntry)
+
+	for _, number := range numbers {
+		for pair, entry := range j.blocks[number] {
+			reverted[pair] = entry
+		}
+
+		delete(j.blocks, number)
+	}
+
+	for number := range j.hashes {
+		if number > block {
+			delete(j.hashes, number)
+		}
+	}
+
+	if j.last > block {
+		j.last = block
+	}
+
+	return reverted
+}
+
+// prune - forgets blocks that are deeper than journal depth
+func (j *Journal) prune() {
+	for number := range j.blocks {
+		if number+j.depth <= j.last {
+			delete(j.blocks, number)
+		}
+	}
+
+	for number := range j.hashes {
+		if number+j.depth <= j.last {
+			delete(j.hashes, number)
+		}
+	}
+}
+
+// Snapshot - returns changes of recent blocks, ordered by block number
+func (j *Journal) Snapshot() []data.GraphSnapshotBlock {
+	blocks := make([]data.GraphSnapshotBlock, 0, len(j.blocks))
+
+	for number, changes := range j.blocks {
+		block := data.GraphSnapshotBlock{
+			Number:  number,
+			Hash:    j.hashes[number],
+			Changes: make([]data.GraphSnapshotChange, 0, len(changes)),
+		}
+
+		for pair, entry := range changes {
+			block.Changes = append(block.Changes, data.GraphSnapshotChange{
+				Pair:     pair,
+				Reserve0: entry.Reserve0,
+				Reserve1: entry.Reserve1,
+				Position: entry.Position,
+			})
+		}
+
+		sort.Slice(block.Changes, func(a, b int) bool {
+			return bytes.Compare(block.Changes[a].Pair.Bytes(), block.Changes[b].Pair.Bytes()) < 0
+		})
+
+		blocks = append(blocks, block)
+	}
+
+	sort.Slice(blocks, func(a, b int) bool {
+		return blocks[a].Number < blocks[b].Number
+	})
+
+	return blocks
+}
+
+// Restore - adds changes of blocks from snapshot
+func (j *Journal) Restore(blocks []data.GraphSnapshotBlock) {
+	if j.depth == 0 {
+		return
+	}
+
+	for _, block := range blocks {
+		changes := make(map[common.Address]JournalEntry, len(block.Changes))
+		for _, change := range block.Changes {
+			changes[change.Pair] = JournalEntry{
+				Reserve0: change.Reserve0,
+				Reserve1: change.Reserve1,
+				Position: change.Position,
+			}
+		}
+
+		j.blocks[block.Number] = changes
+		j.hashes[block.Number] = block.Hash
+
+		if block.Number > j.last {
+			j.last = block.Number
+		}
+	}
+
+	j.prune()
+}
@@ -0,0 +1,48 @@
+package indexer
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	ErrIndexTimeout     = errors.New("indexing time budget exceeded")
+	ErrIndexPathesLimit = errors.New("indexing pathes limit exceeded")
+)
+
+// IndexLimits - bounds of pathes enumeration in Graph.Index and
+// Graph.AddPair. Zero value of any field disables the limit.
+type IndexLimits struct {
+	// MaxHops - maximum number of swaps in one path
+	MaxHops int
+	// MaxPathesPerPair - maximum number of pathes stored for every
+	// (token in, token out) pair. Pathes with less hops are preferred.
+	MaxPathesPerPair int
+	// MaxPathes - maximum number of pathes stored in total
+	MaxPathes int
+	// Timeout - time budget for one indexing, or for finding
+	// pathes through one added pair
+	Timeout time.Duration
+}
+
+func (l IndexLimits) hopsExceeded(hops int) bool {
+	return l.MaxHops > 0 && hops >= l.MaxHops
+}
+
+func (l IndexLimits) pathesExceeded(pathes int) bool {
+	return l.MaxPathes > 0 && pathes >= l.MaxPathes
+}
+
+func (l IndexLimits) deadline(start time.Time) time.Time {
+	if l.Timeout <= 0 {
+		return time.Time{}
+	}
+
+	return start.Add(l.Timeout)
+}
+
+// expired - returns true if deadline is set and already passed
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
@@ -2,29 +2,84 @@ package indexer
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
 	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
 	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
+var _ providers.QuotesProvider = &Graph{}
+
 type Indexer struct {
 	graph *Graph
+	// position - position of the last applied log
+	position data.LogPosition
 
 	logger      *logan.Entry
 	eventsQueue channels.EventQueue
 	pathes      providers.PathesProvider
+
+	snapshots        providers.GraphSnapshotProvider
+	snapshotInterval time.Duration
+
+	// changed - true if graph was changed since the
+	// last search of arbitrages
+	changed bool
+	// searching - 1 while graph snapshot is searched
+	// for arbitrages
+	searching int32
+	// readOnly - if true, graph is restored from snapshot,
+	// but neither it nor pathes are stored
+	readOnly bool
 }
 
 func New(cfg config.Config) *Indexer {
+	ind := NewReplayer(cfg)
+	ind.eventsQueue = cfg.EventsQueue()
+	ind.pathes = providers.NewPathesRedisProvider(cfg.Redis())
+	ind.snapshots = providers.NewGraphSnapshotRedisProvider(cfg.Redis())
+	ind.snapshotInterval = cfg.IndexerCfg().SnapshotInterval
+
+	// API quotes swaps with the graph while it is updated
+	cfg.QuotesView().SetGraph(ind.graph)
+
+	return ind
+}
+
+// NewPending - creates indexer of pending view, that applies events
+// as soon as they are received, before their blocks are confirmed.
+// It starts from snapshot of confirmed graph, as listener sends events
+// after the confirmed block again, and never stores its own.
+func NewPending(cfg config.Config) *Indexer {
+	ind := NewReplayer(cfg)
+	ind.logger = cfg.Log().WithField("view", "pending")
+	ind.eventsQueue = cfg.PendingEventsQueue()
+	ind.snapshots = providers.NewGraphSnapshotRedisProvider(cfg.Redis())
+	ind.readOnly = true
+
+	cfg.PendingQuotesView().SetGraph(ind.graph)
+
+	return ind
+}
+
+// NewReplayer - creates indexer without storage and events queue,
+// that could be used only to replay events
+func NewReplayer(cfg config.Config) *Indexer {
+	limits := cfg.IndexerCfg()
+
 	return &Indexer{
-		graph:       NewGraph(),
-		eventsQueue: cfg.EventsQueue(),
-		logger:      cfg.Log(),
-		pathes:      providers.NewPathesRedisProvider(cfg.Redis()),
+		graph: NewGraph().WithLimits(IndexLimits{
+			MaxHops:          limits.MaxHops,
+			MaxPathesPerPair: limits.MaxPathesPerPair,
+			MaxPathes:        limits.MaxPathes,
+			Timeout:          limits.Timeout,
+		}).WithReorgDepth(cfg.EthereumCfg().ReorgDepth),
+		logger: cfg.Log(),
 	}
 }
 
@@ -36,55 +91,256 @@ func (ind *Indexer) Run(ctx context.Context) error {
 		return errors.Wrap(err, "failed to receive events from queue")
 	}
 
+	if err = ind.restoreGraph(ctx); err != nil {
+		return errors.Wrap(err, "failed to restore graph")
+	}
+
+	// periodic snapshots are disabled if interval is not set
+	var snapshotTicks <-chan time.Time
+	if ind.snapshotInterval > 0 {
+		ticker := time.NewTicker(ind.snapshotInterval)
+		defer ticker.Stop()
+
+		snapshotTicks = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			ind.dumpGraphWithTimeout()
+			if !ind.readOnly {
+				ind.dumpGraphWithTimeout()
+			}
 			return nil
+		case <-snapshotTicks:
+			if err := ind.saveSnapshot(ctx); err != nil {
+				ind.logger.WithError(err).Error("failed to save graph snapshot")
+			}
 		case event := <-eventsSubscription:
 			ind.processEvent(ctx, &event)
 		}
 	}
 }
 
+// Replay - applies events until channel is closed, starting from the
+// snapshot if it is set, and returns snapshot of the resulting graph.
+// Unlike Run, it doesn't touch stored snapshots and pathes.
+func (ind *Indexer) Replay(
+	ctx context.Context, snapshot *data.GraphSnapshot, events <-chan channels.Event,
+) *data.GraphSnapshot {
+	if snapshot != nil {
+		ind.graph.Restore(snapshot)
+		ind.position = snapshot.Position
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ind.snapshot()
+		case event, ok := <-events:
+			if !ok {
+				return ind.snapshot()
+			}
+
+			ind.processEvent(ctx, &event)
+		}
+	}
+}
+
+// restoreGraph - loads graph from the last snapshot if there is one
+func (ind *Indexer) restoreGraph(ctx context.Context) error {
+	snapshot, err := ind.snapshots.GetSnapshot(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get graph snapshot")
+	}
+
+	if snapshot == nil {
+		return nil
+	}
+
+	ind.graph.Restore(snapshot)
+	ind.position = snapshot.Position
+
+	ind.logger.WithFields(logan.F{
+		"block":     snapshot.Position.BlockNumber,
+		"log_index": snapshot.Position.LogIndex,
+		"pairs":     len(snapshot.Edges),
+	}).Info("graph restored from snapshot")
+
+	return nil
+}
+
+func (ind *Indexer) snapshot() *data.GraphSnapshot {
+	snapshot := ind.graph.Snapshot()
+	snapshot.Position = ind.position
+
+	return snapshot
+}
+
+func (ind *Indexer) saveSnapshot(ctx context.Context) error {
+	return ind.snapshots.SetSnapshot(ctx, ind.snapshot())
+}
+
+// applied - returns true if log with such position was already applied
+// to the graph, e.g. if it is replayed after restore from snapshot.
+// Zero position means that event is not caused by log.
+func (ind *Indexer) applied(position data.LogPosition) bool {
+	if position.IsZero() {
+		return false
+	}
+
+	if !position.After(ind.position) {
+		return true
+	}
+
+	if position.BlockNumber != ind.position.BlockNumber {
+		ind.graph.BeginBlock(position.BlockNumber, position.BlockHash)
+	}
+
+	ind.position = position
+
+	return false
+}
+
+// rollback - reverts graph to the state at the end of the block,
+// so logs of canonical chain after it will be applied again
+func (ind *Indexer) rollback(block uint64) {
+	// nothing was applied after the block
+	if ind.position.BlockNumber <= block {
+		return
+	}
+
+	ind.graph.Rollback(block)
+	ind.position = data.EndOfBlock(block)
+	ind.changed = true
+
+	ind.logger.WithField("block", block).Warn("graph rolled back")
+}
+
 func (ind *Indexer) processEvent(ctx context.Context, event *channels.Event) {
 	switch event.Type {
 	case channels.BlockCreationEvent:
-		ind.graph.Index()
+		ind.reportArbitrages(event.BlockCreation.Block)
+	case channels.RollbackEvent:
+		ind.rollback(event.Rollback.Block)
 	case channels.ReservesUpdateEvent:
-		ind.graph.UpdateReserves(
-			event.ReservesUpdate.Token0,
-			event.ReservesUpdate.Token1,
-			event.ReservesUpdate.Reserve0Delta,
-			event.ReservesUpdate.Reserve1Delta,
+		if ind.applied(event.ReservesUpdate.Position) {
+			return
+		}
+
+		// Sync logs carry absolute reserves, so stale ones are
+		// just ignored by the graph
+		ind.graph.SetReserves(
+			event.ReservesUpdate.Address,
+			event.ReservesUpdate.Reserve0,
+			event.ReservesUpdate.Reserve1,
+			event.ReservesUpdate.Position,
 		)
+		ind.changed = true
+	case channels.PairActionEvent:
+		ind.logger.WithFields(logan.F{
+			"type":  event.PairAction.Type.String(),
+			"pair":  event.PairAction.Address.Hex(),
+			"block": event.PairAction.Position.BlockNumber,
+		}).Debug("pair action")
 	case channels.PairCreationEvent:
-		ind.graph.AddEdge(
+		// pair loaded from node is not a log, so it doesn't move
+		// position of applied logs, and older logs of other pairs
+		// that follow it are still applied
+		position := event.PairCreation.Position
+		if !position.IsEndOfBlock() && ind.applied(position) {
+			return
+		}
+
+		edge := NewEdge(
+			event.PairCreation.Address,
 			event.PairCreation.Token0,
 			event.PairCreation.Token1,
 			event.PairCreation.Reserve0,
 			event.PairCreation.Reserve1,
 		)
+		edge.Factory = event.PairCreation.Factory
+		edge.Fee = event.PairCreation.Fee
+		edge.Position = event.PairCreation.Position
+
+		ind.graph.AddPair(edge)
+		ind.changed = true
 	}
 }
 
+// reportArbitrages - searches for arbitrages with reserves of the
+// previous block and logs them, if graph was changed since the last
+// search. Search goes through the whole graph, so it is made with its
+// copy in background, and skipped if the previous one is not finished.
+func (ind *Indexer) reportArbitrages(block uint64) {
+	if !ind.changed {
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(&ind.searching, 0, 1) {
+		return
+	}
+
+	ind.changed = false
+	snapshot := ind.graph.ArbitrageSnapshot()
+
+	go func() {
+		defer atomic.StoreInt32(&ind.searching, 0)
+
+		for _, arbitrage := range snapshot.FindArbitrages() {
+			ind.logArbitrage(block, arbitrage)
+		}
+	}()
+}
+
+func (ind *Indexer) logArbitrage(block uint64, arbitrage *Arbitrage) {
+	pairs := make([]string, len(arbitrage.Path.Pairs))
+	for i, pair := range arbitrage.Path.Pairs {
+		pairs[i] = pair.Hex()
+	}
+
+	tokens := make([]string, len(arbitrage.Path.Tokens))
+	for i, token := range arbitrage.Path.Tokens {
+		tokens[i] = token.Hex()
+	}
+
+	ind.logger.WithFields(logan.F{
+		"block":     block,
+		"tokens":    tokens,
+		"pairs":     pairs,
+		"amount_in": arbitrage.AmountIn().String(),
+		"profit":    arbitrage.Profit().String(),
+	}).Info("arbitrage found")
+}
+
+// dumpGraphWithTimeout - dumps graph and waits until it is done,
+// but not longer than defaultDumpTimeout
 func (ind *Indexer) dumpGraphWithTimeout() {
 	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), defaultDumpTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
 
 	go func() {
+		defer close(done)
+
 		if err := ind.dumpGraph(ctxWithTimeout); err != nil {
 			ind.logger.WithError(err).Error("failed to dump graph")
 		}
 	}()
 
 	select {
+	case <-done:
 	case <-ctxWithTimeout.Done():
-		cancel()
+		ind.logger.Warn("graph dump timed out")
 	}
 }
 
 func (ind *Indexer) dumpGraph(ctx context.Context) error {
-	for edge, pathes := range ind.graph.pathesMap.m {
+	if err := ind.saveSnapshot(ctx); err != nil {
+		return errors.Wrap(err, "failed to save graph snapshot")
+	}
+
+	for edge, pathes := range ind.graph.Pathes() {
 		err := ind.pathes.SetPathes(ctx, edge.Token0, edge.Token1, pathes)
 		if err != nil {
 			return errors.Wrap(err, "failed to dump pathes", logan.F{
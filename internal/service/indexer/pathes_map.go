@@ -12,6 +12,10 @@ type PathesMap struct {
 	mutex sync.RWMutex
 
 	m map[EdgeKey][]data.Path
+	// maxPerPair - maximum number of pathes for every key,
+	// zero means no limit
+	maxPerPair int
+	total      int
 }
 
 func NewPathesMap() *PathesMap {
@@ -20,32 +24,113 @@ func NewPathesMap() *PathesMap {
 	}
 }
 
-func (pm *PathesMap) AddPaths(pathes ...data.Path) {
+// SetMaxPerPair - sets maximum number of pathes stored for every pair
+// of tokens. Pathes that are already stored are not affected.
+func (pm *PathesMap) SetMaxPerPair(maxPerPair int) *PathesMap {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
+	pm.maxPerPair = maxPerPair
+
+	return pm
+}
+
+// AddPaths - stores pathes by their first and last tokens. Pathes are
+// kept sorted by number of hops, so if there is no space left for the
+// pair, the longest path is dropped. Returns number of stored pathes.
+func (pm *PathesMap) AddPaths(pathes ...data.Path) int {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	var added int
+
+	// pathes are directed, so path from token0 to token1 is stored
+	// separately from the path in opposite direction
 	for _, path := range pathes {
-		key := EdgeKey{path[0], path[len(path)-1]}
-		keyInvers := EdgeKey{path[len(path)-1], path[0]}
+		if pm.addPath(path) {
+			added++
+		}
+	}
+
+	return added
+}
+
+func (pm *PathesMap) addPath(path data.Path) bool {
+	key := EdgeKey{path.First(), path.Last()}
+	stored := pm.m[key]
 
-		if _, ok := pm.m[key]; ok {
-			pm.m[key] = append(pm.m[key], path)
-			return
+	position := len(stored)
+	for i, elem := range stored {
+		if elem.Equal(path) {
+			return false
 		}
 
-		if _, ok := pm.m[keyInvers]; ok {
-			pm.m[keyInvers] = append(pm.m[keyInvers], path)
-			return
+		if position == len(stored) && elem.Hops() > path.Hops() {
+			position = i
 		}
+	}
 
-		pm.m[key] = []data.Path{path}
+	if pm.maxPerPair > 0 && position >= pm.maxPerPair {
+		return false
 	}
+
+	// new slice is created, because old one could be used
+	// by readers that got it from GetPath
+	updated := make([]data.Path, 0, len(stored)+1)
+	updated = append(updated, stored[:position]...)
+	updated = append(updated, path)
+	updated = append(updated, stored[position:]...)
+
+	pm.total++
+
+	if pm.maxPerPair > 0 && len(updated) > pm.maxPerPair {
+		updated = updated[:pm.maxPerPair]
+		pm.total--
+	}
+
+	pm.m[key] = updated
+
+	return true
+}
+
+// Full - returns true if there is no space left for pathes
+// from token0 to token1
+func (pm *PathesMap) Full(token0, token1 common.Address) bool {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	return pm.maxPerPair > 0 && len(pm.m[EdgeKey{token0, token1}]) >= pm.maxPerPair
+}
+
+// Len - returns total number of stored pathes
+func (pm *PathesMap) Len() int {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	return pm.total
 }
 
 func (pm *PathesMap) GetPath(token0, token1 common.Address) []data.Path {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
 	if pathes, ok := pm.m[EdgeKey{token0, token1}]; ok {
 		return pathes
 	}
 
 	return []data.Path{}
 }
+
+// Copy - returns stored pathes by their first and last tokens. Slices
+// of pathes are not copied, as they are never modified in place.
+func (pm *PathesMap) Copy() map[EdgeKey][]data.Path {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	pathes := make(map[EdgeKey][]data.Path, len(pm.m))
+	for key, stored := range pm.m {
+		pathes[key] = stored
+	}
+
+	return pathes
+}
@@ -11,3 +11,14 @@ func Run(ctx context.Context, cfg config.Config) {
 		cfg.Log().WithError(err).Panic("indexer running failed")
 	}
 }
+
+// RunPending - runs indexer of pending view, if it is enabled
+func RunPending(ctx context.Context, cfg config.Config) {
+	if !cfg.ConfirmationsCfg().Pending {
+		return
+	}
+
+	if err := NewPending(cfg).Run(ctx); err != nil {
+		cfg.Log().WithError(err).Panic("pending indexer running failed")
+	}
+}
@@ -0,0 +1,234 @@
+package indexer
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+)
+
+// DefaultSplitParts - number of parts amount in is divided into
+// when it is distributed between pathes
+const DefaultSplitParts = 20
+
+// BestSplit - distributes amountIn between at most maxPathes pathes from
+// input to output token to maximize total output. Amount is divided into
+// parts, and every part is sent to the path that gives the most for it
+// with reserves changed by previous parts, so pairs shared by several
+// pathes are taken into account. The whole amount is always allocated,
+// even if liquidity runs out. Returns nil if there is no path.
+func (g *Graph) BestSplit(
+	input, output common.Address, amountIn *big.Int, maxPathes, parts int,
+) *data.Split {
+	g.mux.RLock()
+	defer g.mux.RUnlock()
+
+	if parts <= 0 {
+		parts = DefaultSplitParts
+	}
+
+	chunks := splitAmount(amountIn, parts)
+	pathes := g.topPathes(g.pathesMap.GetPath(input, output), chunks[0], maxPathes)
+	if len(pathes) == 0 {
+		return nil
+	}
+
+	var (
+		sim = newSimulation(g)
+		// allocations - amount in sent to every path
+		allocations = make([]*big.Int, len(pathes))
+	)
+
+	for c, chunk := range chunks {
+		var (
+			best        = -1
+			bestAmounts []*big.Int
+		)
+
+		for i, path := range pathes {
+			amounts := sim.amountsOut(path, chunk)
+			if best == -1 || amounts[len(amounts)-1].Cmp(bestAmounts[len(bestAmounts)-1]) > 0 {
+				best, bestAmounts = i, amounts
+			}
+		}
+
+		if allocations[best] == nil {
+			allocations[best] = big.NewInt(0)
+		}
+
+		// liquidity of all pathes ran out, so the rest of amount gives
+		// nothing wherever it goes, and it is sent to the best path
+		// for this part, as split should swap the whole amount in
+		if bestAmounts[len(bestAmounts)-1].Sign() <= 0 {
+			for _, rest := range chunks[c:] {
+				allocations[best].Add(allocations[best], rest)
+			}
+			break
+		}
+
+		sim.apply(pathes[best], bestAmounts)
+
+		allocations[best].Add(allocations[best], chunk)
+	}
+
+	split := g.executeSplit(pathes, allocations)
+
+	// swapping by parts costs more fees than one swap, so if
+	// all parts went to the same path, single swap is better
+	single, ok := g.quote(pathes[0], amountIn)
+	if ok && (split == nil || single.AmountOut().Cmp(split.AmountOut()) > 0) {
+		return &data.Split{Quotes: []*data.Quote{single}}
+	}
+
+	return split
+}
+
+// executeSplit - calculates amounts as if allocated amounts were swapped
+// along their pathes one after another in a single swap per path
+func (g *Graph) executeSplit(pathes []data.Path, allocations []*big.Int) *data.Split {
+	var (
+		sim    = newSimulation(g)
+		quotes = make([]*data.Quote, 0, len(pathes))
+	)
+
+	for i, path := range pathes {
+		if allocations[i] == nil {
+			continue
+		}
+
+		reservesIn, reservesOut, fees := sim.pathReserves(path)
+
+		amounts := math.GetAmountsOut(reservesIn, reservesOut, fees, allocations[i])
+
+		quotes = append(quotes, &data.Quote{
+			Path:      path,
+			Amounts:   amounts,
+			MidPrices: midPrices(reservesIn, reservesOut),
+		})
+
+		sim.apply(path, amounts)
+	}
+
+	if len(quotes) == 0 {
+		return nil
+	}
+
+	return &data.Split{Quotes: quotes}
+}
+
+// topPathes - returns at most limit pathes that give the most for amountIn
+func (g *Graph) topPathes(pathes []data.Path, amountIn *big.Int, limit int) []data.Path {
+	quotes := make([]*data.Quote, 0, len(pathes))
+
+	for _, path := range pathes {
+		if quote, ok := g.quote(path, amountIn); ok {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].AmountOut().Cmp(quotes[j].AmountOut()) > 0
+	})
+
+	if limit > 0 && len(quotes) > limit {
+		quotes = quotes[:limit]
+	}
+
+	result := make([]data.Path, len(quotes))
+	for i, quote := range quotes {
+		result[i] = quote.Path
+	}
+
+	return result
+}
+
+// splitAmount - divides amount into parts, where the last one
+// also gets the remainder. Parts are merged if amount is too small.
+func splitAmount(amount *big.Int, parts int) []*big.Int {
+	if big.NewInt(int64(parts)).Cmp(amount) > 0 {
+		parts = int(amount.Int64())
+	}
+
+	if parts <= 1 {
+		return []*big.Int{new(big.Int).Set(amount)}
+	}
+
+	chunk, remainder := new(big.Int).QuoRem(amount, big.NewInt(int64(parts)), new(big.Int))
+
+	chunks := make([]*big.Int, parts)
+	for i := range chunks {
+		chunks[i] = new(big.Int).Set(chunk)
+	}
+	chunks[parts-1].Add(chunks[parts-1], remainder)
+
+	return chunks
+}
+
+// simulation - copy of pairs reserves that are changed by simulated swaps
+type simulation struct {
+	graph    *Graph
+	reserves map[*Edge][2]*big.Int
+}
+
+func newSimulation(graph *Graph) *simulation {
+	return &simulation{
+		graph:    graph,
+		reserves: make(map[*Edge][2]*big.Int),
+	}
+}
+
+func (s *simulation) edgeReserves(edge *Edge, tokenIn common.Address) (reserveIn, reserveOut *big.Int) {
+	reserves, ok := s.reserves[edge]
+	if !ok {
+		reserves = [2]*big.Int{
+			new(big.Int).Set(edge.Reserve0),
+			new(big.Int).Set(edge.Reserve1),
+		}
+		s.reserves[edge] = reserves
+	}
+
+	if tokenIn == edge.Token0 {
+		return reserves[0], reserves[1]
+	}
+
+	return reserves[1], reserves[0]
+}
+
+// pathReserves - returns simulated reserves of every hop of the path
+// oriented in the swap direction and fees of the hops
+func (s *simulation) pathReserves(path data.Path) (reservesIn, reservesOut []*big.Int, fees []math.Fee) {
+	reservesIn = make([]*big.Int, 0, path.Hops())
+	reservesOut = make([]*big.Int, 0, path.Hops())
+	fees = make([]math.Fee, 0, path.Hops())
+
+	for i, pair := range path.Pairs {
+		edge := s.graph.edges[pair]
+		reserveIn, reserveOut := s.edgeReserves(edge, path.Tokens[i])
+
+		reservesIn = append(reservesIn, reserveIn)
+		reservesOut = append(reservesOut, reserveOut)
+		fees = append(fees, edge.Fee)
+	}
+
+	return reservesIn, reservesOut, fees
+}
+
+// amountsOut - calculates amounts on every hop with simulated reserves
+func (s *simulation) amountsOut(path data.Path, amountIn *big.Int) []*big.Int {
+	reservesIn, reservesOut, fees := s.pathReserves(path)
+
+	return math.GetAmountsOut(reservesIn, reservesOut, fees, amountIn)
+}
+
+// apply - changes simulated reserves as if swap with amounts was made
+func (s *simulation) apply(path data.Path, amounts []*big.Int) {
+	for i, pair := range path.Pairs {
+		reserveIn, reserveOut := s.edgeReserves(s.graph.edges[pair], path.Tokens[i])
+
+		reserveIn.Add(reserveIn, amounts[i])
+		reserveOut.Sub(reserveOut, amounts[i+1])
+	}
+}
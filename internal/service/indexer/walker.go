@@ -8,18 +8,17 @@ import (
 type Walker struct {
 	path data.Path
 
-	root, current common.Address
+	current common.Address
 }
 
 func NewWalker(root common.Address) *Walker {
 	return &Walker{
-		root:    root,
 		current: root,
 		path:    data.NewPath(root),
 	}
 }
 
-func (w *Walker) GetPath() []common.Address {
+func (w *Walker) GetPath() data.Path {
 	return w.path
 }
 
@@ -27,25 +26,27 @@ func (w *Walker) Current() common.Address {
 	return w.current
 }
 
-func (w *Walker) Next(routes map[common.Address]*Edge) (bool, []*Walker) {
+// Next - spawns walker for every pair to the token that is not visited
+// yet. Every spawned walker's path is a complete route from root.
+func (w *Walker) Next(routes map[common.Address][]*Edge) []*Walker {
 	walkers := make([]*Walker, 0)
 
-	for next := range routes {
+	for next, edges := range routes {
 		if w.path.Contains(next) {
 			continue
 		}
 
-		path := w.path.Copy()
-		path.Append(next)
+		// every pool between the same tokens is a separate route
+		for _, edge := range edges {
+			path := w.path.Copy()
+			path.Append(edge.Address, next)
 
-		walkers = append(walkers, &Walker{
-			path:    path,
-			root:    w.root,
-			current: next,
-		})
+			walkers = append(walkers, &Walker{
+				path:    path,
+				current: next,
+			})
+		}
 	}
 
-	// If there are no more sub walkers (walker has no next routes),
-	// we are done
-	return len(walkers) > 0, walkers
+	return walkers
 }
@@ -1,17 +1,19 @@
 package indexer
 
 import (
-	"math/big"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
 type weightsMapKey struct {
-	Token0 common.Address
-	Token1 common.Address
+	TokenIn common.Address
+	Pair    common.Address
 }
 
+// WeightsMap - directed weights of the graph edges, where weight of
+// swap of tokenIn through the pair is stored by (tokenIn, pair) key,
+// so parallel pools between the same tokens have separate weights
 type WeightsMap struct {
 	m sync.Map
 }
@@ -20,16 +22,23 @@ func NewWeightsMap() *WeightsMap {
 	return &WeightsMap{}
 }
 
-func (wm *WeightsMap) Get(token0, token1 common.Address) (*big.Float, bool) {
-	res, ok := wm.m.Load(weightsMapKey{token0, token1})
+func (wm *WeightsMap) Get(tokenIn, pair common.Address) (float64, bool) {
+	res, ok := wm.m.Load(weightsMapKey{tokenIn, pair})
 	if !ok {
-		return nil, false
+		return 0, false
 	}
-	value := res.(*big.Float)
 
-	return new(big.Float).Set(value), true
+	return res.(float64), true
 }
 
-func (wm *WeightsMap) Set(token0, token1 common.Address, value *big.Float) {
-	wm.m.Store(weightsMapKey{token0, token1}, new(big.Float).Set(value))
+func (wm *WeightsMap) Set(tokenIn, pair common.Address, value float64) {
+	wm.m.Store(weightsMapKey{tokenIn, pair}, value)
+}
+
+// Range calls f sequentially for each directed edge and its weight.
+func (wm *WeightsMap) Range(f func(tokenIn, pair common.Address, weight float64) bool) {
+	wm.m.Range(func(key, value interface{}) bool {
+		k := key.(weightsMapKey)
+		return f(k.TokenIn, k.Pair, value.(float64))
+	})
 }
@@ -0,0 +1,154 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
+)
+
+// backfillStart - returns block from which historical logs should be
+// loaded. It is the last block listener has seen, the block of graph
+// snapshot or the last confirmed block if they are older, or configured
+// start block on the first run.
+// Returns false if backfill is disabled.
+func (l *Listener) backfillStart(ctx context.Context) (uint64, bool, error) {
+	block, err := l.currentBlock.CurrentBlock(ctx)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to get last block")
+	}
+
+	// logs after snapshot should be replayed, indexer
+	// will skip the ones that were already applied
+	if l.snapshotBlock != 0 && l.snapshotBlock < block {
+		block = l.snapshotBlock
+	}
+
+	// events of unconfirmed blocks were lost with restart
+	if l.confirmedBlock != nil {
+		confirmed, err := l.confirmedBlock.CurrentBlock(ctx)
+		if err != nil {
+			return 0, false, errors.Wrap(err, "failed to get confirmed block")
+		}
+
+		if confirmed != 0 && confirmed < block {
+			block = confirmed
+		}
+	}
+
+	if block == 0 {
+		block = l.backfillCfg.StartBlock
+	}
+
+	return block, block != 0, nil
+}
+
+// backfill - loads logs from blocks [from, to] with eth_getLogs by
+// ranges and processes them in order. Range is halved when node
+// complains about results limit, and doubled after successful request
+// up to configured maximum.
+func (l *Listener) backfill(
+	ctx context.Context, query ethereum.FilterQuery, from, to uint64,
+) error {
+	batch := l.backfillCfg.BatchSize
+
+	l.logger.WithFields(logan.F{
+		"from": from,
+		"to":   to,
+	}).Info("backfill started")
+
+	for from <= to {
+		end := from + batch - 1
+		if end > to {
+			end = to
+		}
+
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = new(big.Int).SetUint64(end)
+
+		logs, err := l.logs.FilterLogs(ctx, query)
+		if err != nil {
+			if multiclient.IsLogsLimitError(err) && batch > 1 {
+				batch /= 2
+
+				l.logger.WithError(err).WithFields(logan.F{
+					"from":  from,
+					"batch": batch,
+				}).Debug("logs limit exceeded, reducing range")
+				continue
+			}
+
+			return errors.Wrap(err, "failed to filter logs", logan.F{
+				"from": from,
+				"to":   end,
+			})
+		}
+
+		sortLogs(logs)
+
+		for i := range logs {
+			// log was already processed before reconnection
+			if !logPosition(&logs[i]).After(l.lastLog) {
+				continue
+			}
+
+			if err := l.backfillLog(ctx, &logs[i], to); err != nil {
+				l.logger.WithError(err).Error("failed to handle event")
+			}
+		}
+
+		l.logger.WithFields(logan.F{
+			"from": from,
+			"to":   end,
+			"logs": len(logs),
+		}).Debug("blocks backfilled")
+
+		from = end + 1
+
+		if batch < l.backfillCfg.MaxBatchSize {
+			batch *= 2
+			if batch > l.backfillCfg.MaxBatchSize {
+				batch = l.backfillCfg.MaxBatchSize
+			}
+		}
+	}
+
+	// blocks without logs are processed too
+	if end := data.EndOfBlock(to); end.After(l.lastLog) {
+		l.lastLog = end
+	}
+
+	l.logger.WithField("to", to).Info("backfill finished")
+
+	return nil
+}
+
+// backfillLog - applies historical log the same way as the live one,
+// checking that its block continues known chain. Blocks deeper than
+// reorg depth from the end of backfill are final and can't be rolled
+// back, so their parents are not requested, but hashes are still
+// recorded, so the first recent block is checked against them.
+func (l *Listener) backfillLog(ctx context.Context, log *types.Log, to uint64) error {
+	if to-log.BlockNumber < l.ethereumCfg.ReorgDepth {
+		return l.proccessLog(ctx, log)
+	}
+
+	l.archiveLog(log)
+
+	return l.applyLog(ctx, log)
+}
+
+// sortLogs - orders logs by their position in chain, as node
+// doesn't guarantee the order of eth_getLogs results
+func sortLogs(logs []types.Log) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logPosition(&logs[j]).After(logPosition(&logs[i]))
+	})
+}
@@ -0,0 +1,45 @@
+package listener
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff - exponentially growing delay between reconnections,
+// with random jitter, so restarted listeners don't hit the node
+// at the same time
+type backoff struct {
+	min, max time.Duration
+	current  time.Duration
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{
+		min: min,
+		max: max,
+	}
+}
+
+// Next - returns delay before the next attempt, that is picked
+// randomly from [d/2, d], where d is doubled after every attempt
+func (b *backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.min
+	} else {
+		b.current *= 2
+	}
+
+	if b.current > b.max {
+		b.current = b.max
+	}
+
+	half := b.current / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Reset - makes the next delay minimal again, should be called
+// after successful attempt
+func (b *backoff) Reset() {
+	b.current = 0
+}
@@ -0,0 +1,77 @@
+package listener
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// blocksHistory - hashes of recent blocks that listener has seen,
+// which are compared with new ones to detect reorganizations
+type blocksHistory struct {
+	depth  uint64
+	hashes map[uint64]common.Hash
+	last   uint64
+}
+
+func newBlocksHistory(depth uint64) *blocksHistory {
+	return &blocksHistory{
+		depth:  depth,
+		hashes: make(map[uint64]common.Hash),
+	}
+}
+
+func (h *blocksHistory) Hash(number uint64) (common.Hash, bool) {
+	hash, ok := h.hashes[number]
+	return hash, ok
+}
+
+// Last - returns number of the latest known block
+func (h *blocksHistory) Last() uint64 {
+	return h.last
+}
+
+// Add - stores hash of the block and forgets blocks
+// that are deeper than history depth
+func (h *blocksHistory) Add(number uint64, hash common.Hash) {
+	h.hashes[number] = hash
+
+	if number <= h.last {
+		return
+	}
+
+	h.last = number
+
+	for known := range h.hashes {
+		if known+h.depth <= h.last {
+			delete(h.hashes, known)
+		}
+	}
+}
+
+// Numbers - returns numbers of known blocks from the latest one
+func (h *blocksHistory) Numbers() []uint64 {
+	numbers := make([]uint64, 0, len(h.hashes))
+	for number := range h.hashes {
+		numbers = append(numbers, number)
+	}
+
+	sort.Slice(numbers, func(i, j int) bool {
+		return numbers[i] > numbers[j]
+	})
+
+	return numbers
+}
+
+// Rollback - forgets blocks after number
+func (h *blocksHistory) Rollback(number uint64) {
+	for known := range h.hashes {
+		if known > number {
+			delete(h.hashes, known)
+		}
+	}
+
+	if h.last > number {
+		h.last = number
+	}
+}
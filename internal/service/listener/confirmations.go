@@ -0,0 +1,266 @@
+package listener
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
+)
+
+var _ channels.EventQueue = &confirmedQueue{}
+
+// confirmedQueue - buffers events by blocks and sends them to the queue
+// only when their blocks are confirmed, so its consumers are not affected
+// by reorgs shallower than confirmation depth. Pending queue, if set,
+// receives every event immediately. Confirmed block is advanced both by
+// events of new blocks and by new heads of chain, so events of quiet
+// pairs are not kept buffered until the next log.
+type confirmedQueue struct {
+	queue   channels.EventQueue
+	pending channels.EventQueue
+
+	client *multiclient.Client
+	cfg    config.ConfirmationsCfg
+	logger *logan.Entry
+	// confirmedBlock - the last block which events were sent
+	// to the queue, logs are replayed from it after restart
+	confirmedBlock providers.CurrentBlockProvider
+
+	mux    sync.Mutex
+	buffer []blockEvent
+	// head - the latest known block of chain
+	head uint64
+	// confirmed - the latest block that is known to be confirmed
+	confirmed uint64
+}
+
+type blockEvent struct {
+	block uint64
+	event channels.Event
+}
+
+func newConfirmedQueue(
+	queue, pending channels.EventQueue, client *multiclient.Client,
+	cfg config.ConfirmationsCfg, confirmedBlock providers.CurrentBlockProvider,
+	logger *logan.Entry,
+) *confirmedQueue {
+	return &confirmedQueue{
+		queue:          queue,
+		pending:        pending,
+		client:         client,
+		cfg:            cfg,
+		logger:         logger,
+		confirmedBlock: confirmedBlock,
+	}
+}
+
+func (c *confirmedQueue) Receive(ctx context.Context) (<-chan channels.Event, error) {
+	return c.queue.Receive(ctx)
+}
+
+// Send - sends events to the pending queue and buffers them
+// until their blocks are confirmed
+func (c *confirmedQueue) Send(ctx context.Context, events ...channels.Event) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for _, event := range events {
+		if c.pending != nil {
+			if err := c.pending.Send(ctx, event); err != nil {
+				return errors.Wrap(err, "failed to send pending event")
+			}
+		}
+
+		if err := c.add(ctx, event); err != nil {
+			return errors.Wrap(err, "failed to add event")
+		}
+	}
+
+	return nil
+}
+
+func (c *confirmedQueue) add(ctx context.Context, event channels.Event) error {
+	if event.Type == channels.RollbackEvent {
+		return c.rollback(ctx, event)
+	}
+
+	block, ok := eventBlock(event)
+	if !ok {
+		// event is not caused by log, e.g. pair is registered
+		// on start, so it is just kept in order with others
+		if len(c.buffer) == 0 {
+			return c.queue.Send(ctx, event)
+		}
+
+		block = c.buffer[len(c.buffer)-1].block
+	}
+
+	c.buffer = append(c.buffer, blockEvent{
+		block: block,
+		event: event,
+	})
+
+	if block > c.head {
+		c.head = block
+		c.refresh(ctx)
+	}
+
+	return c.flush(ctx)
+}
+
+// Watch - requests head of chain every interval and sends events
+// that became confirmed, until context is canceled
+func (c *confirmedQueue) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head, err := c.client.BlockNumber(ctx)
+		if err != nil {
+			c.logger.WithError(err).Warn("failed to get head block number")
+			continue
+		}
+
+		if err := c.newHead(ctx, head); err != nil {
+			c.logger.WithError(err).Error("failed to send confirmed events")
+		}
+	}
+}
+
+// newHead - advances confirmed block to the new head of chain
+// and sends buffered events that became confirmed
+func (c *confirmedQueue) newHead(ctx context.Context, head uint64) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if head <= c.head {
+		return nil
+	}
+
+	c.head = head
+	c.refresh(ctx)
+
+	return c.flush(ctx)
+}
+
+// refresh - updates the latest confirmed block. Failure to get
+// tagged block is not critical, as events are just kept buffered
+// until the next block.
+func (c *confirmedQueue) refresh(ctx context.Context) {
+	if c.cfg.Tag == "" {
+		if c.head > c.cfg.Depth && c.head-c.cfg.Depth > c.confirmed {
+			c.confirmed = c.head - c.cfg.Depth
+		}
+		return
+	}
+
+	// events are sent immediately while old blocks are backfilled
+	if c.head <= c.confirmed {
+		return
+	}
+
+	header, err := c.client.HeaderByTag(ctx, c.cfg.Tag)
+	if err != nil {
+		c.logger.WithError(err).WithField("tag", c.cfg.Tag).Warn("failed to get confirmed block")
+		return
+	}
+
+	if number := header.Number.Uint64(); number > c.confirmed {
+		c.confirmed = number
+	}
+}
+
+// flush - sends events of confirmed blocks to the queue
+func (c *confirmedQueue) flush(ctx context.Context) error {
+	n := 0
+	for n < len(c.buffer) && c.buffer[n].block <= c.confirmed {
+		n++
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	events := make([]channels.Event, n)
+	for i := range events {
+		events[i] = c.buffer[i].event
+	}
+
+	last := c.buffer[n-1].block
+	c.buffer = append(c.buffer[:0], c.buffer[n:]...)
+
+	if err := c.queue.Send(ctx, events...); err != nil {
+		return errors.Wrap(err, "failed to send confirmed events")
+	}
+
+	return errors.Wrap(c.confirmedBlock.UpdateBlock(ctx, last), "failed to update confirmed block")
+}
+
+// rollback - drops events of reverted blocks, and sends rollback
+// to the queue only if events after the block were already sent
+func (c *confirmedQueue) rollback(ctx context.Context, event channels.Event) error {
+	block := event.Rollback.Block
+
+	n := len(c.buffer)
+	for n > 0 && c.buffer[n-1].block > block {
+		n--
+	}
+	c.buffer = c.buffer[:n]
+
+	if c.head > block {
+		c.head = block
+	}
+
+	sent, err := c.confirmedBlock.CurrentBlock(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get confirmed block")
+	}
+
+	if block >= sent {
+		return nil
+	}
+
+	c.logger.WithFields(logan.F{
+		"block":     block,
+		"confirmed": sent,
+	}).Warn("reorg is deeper than confirmations")
+
+	if err := c.queue.Send(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to send rollback event")
+	}
+
+	return errors.Wrap(c.confirmedBlock.UpdateBlock(ctx, block), "failed to update confirmed block")
+}
+
+// eventBlock - returns block of the log that caused event,
+// false means that event is not caused by log
+func eventBlock(event channels.Event) (uint64, bool) {
+	var position data.LogPosition
+
+	switch event.Type {
+	case channels.BlockCreationEvent:
+		return event.BlockCreation.Block, true
+	case channels.ReservesUpdateEvent:
+		position = event.ReservesUpdate.Position
+	case channels.PairActionEvent:
+		position = event.PairAction.Position
+	case channels.PairCreationEvent:
+		position = event.PairCreation.Position
+	}
+
+	return position.BlockNumber, !position.IsZero()
+}
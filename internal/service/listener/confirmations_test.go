@@ -0,0 +1,114 @@
+package listener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+)
+
+// syncAt - returns reserves update caused by log in the block
+func syncAt(block uint64) channels.Event {
+	return channels.Event{
+		Type: channels.ReservesUpdateEvent,
+		ReservesUpdate: &channels.ReservesUpdate{
+			Position: data.LogPosition{BlockNumber: block},
+		},
+	}
+}
+
+// received - returns events that are already sent to the channel
+func received(events <-chan channels.Event) []uint64 {
+	blocks := make([]uint64, 0)
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type == channels.RollbackEvent {
+				blocks = append(blocks, event.Rollback.Block)
+				continue
+			}
+
+			block, _ := eventBlock(event)
+			blocks = append(blocks, block)
+		default:
+			return blocks
+		}
+	}
+}
+
+func Test_ConfirmedQueue(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		queue          = channels.NewEventChan()
+		pending        = channels.NewEventChan()
+		confirmedBlock = providers.NewBlockMemoryProvider()
+	)
+
+	confirmed, err := queue.Receive(ctx)
+	require.NoError(t, err)
+	unconfirmed, err := pending.Receive(ctx)
+	require.NoError(t, err)
+
+	q := newConfirmedQueue(
+		queue, pending, nil, config.ConfirmationsCfg{Depth: 2},
+		confirmedBlock, logan.New(),
+	)
+
+	t.Run("events are buffered until confirmed", func(t *testing.T) {
+		require.NoError(t, q.Send(ctx, syncAt(10), syncAt(11)))
+
+		require.Empty(t, received(confirmed))
+		require.Equal(t, []uint64{10, 11}, received(unconfirmed))
+
+		require.NoError(t, q.Send(ctx, syncAt(12)))
+
+		require.Equal(t, []uint64{10}, received(confirmed))
+		require.Equal(t, []uint64{12}, received(unconfirmed))
+	})
+
+	t.Run("new head sends events without new logs", func(t *testing.T) {
+		require.NoError(t, q.newHead(ctx, 14))
+
+		require.Equal(t, []uint64{11, 12}, received(confirmed))
+
+		block, err := confirmedBlock.CurrentBlock(ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(12), block)
+	})
+
+	t.Run("shallow reorg drops buffered events", func(t *testing.T) {
+		require.NoError(t, q.Send(ctx, syncAt(15), syncAt(16)))
+		require.Equal(t, []uint64{15, 16}, received(unconfirmed))
+
+		require.NoError(t, q.Send(ctx, channels.Event{
+			Type:     channels.RollbackEvent,
+			Rollback: &channels.Rollback{Block: 15},
+		}))
+
+		require.NoError(t, q.newHead(ctx, 20))
+
+		// rollback is not sent, as nothing after block was
+		require.Equal(t, []uint64{15}, received(confirmed))
+	})
+
+	t.Run("deep reorg is sent", func(t *testing.T) {
+		require.NoError(t, q.Send(ctx, channels.Event{
+			Type:     channels.RollbackEvent,
+			Rollback: &channels.Rollback{Block: 11},
+		}))
+
+		require.Equal(t, []uint64{11}, received(confirmed))
+
+		block, err := confirmedBlock.CurrentBlock(ctx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(11), block)
+	})
+}
@@ -0,0 +1,109 @@
+package listener
+
+import (
+	"context"
+	"sync/atomic"
+
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	workerspool "github.com/Velnbur/uniswapv2-indexer/pkg/workers-pool"
+)
+
+// discoverFactoryContracts - registers all pairs of factory, enumerating
+// them by index. Pairs are loaded in batches with multicall, several
+// batches are loaded concurrently, and after every round of them progress
+// is saved, so addresses of already discovered pairs are taken from cache
+// after restart.
+func (l *Listener) discoverFactoryContracts(ctx context.Context, factory *contracts.UniswapV2Factory) error {
+	length, err := factory.AllPairLength(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get pairs amount")
+	}
+
+	discovered, err := factory.DiscoveredPairs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get discovery progress")
+	}
+
+	logger := l.logger.WithField("factory", factory.Name)
+
+	logger.WithFields(logan.F{
+		"pairs":      length,
+		"discovered": discovered,
+	}).Info("starting pairs discovery")
+
+	var registered int64
+
+	batchSize := l.discoveryCfg.BatchSize
+	roundSize := batchSize * uint64(l.discoveryCfg.Workers)
+
+	for round := uint64(0); round < length; round += roundSize {
+		roundEnd := round + roundSize
+		if roundEnd > length {
+			roundEnd = length
+		}
+
+		batches := (roundEnd - round + batchSize - 1) / batchSize
+		wp := workerspool.NewWorkingPool(l.discoveryCfg.Workers, int64(batches))
+
+		for from := round; from < roundEnd; from += batchSize {
+			from, to := from, from+batchSize
+			if to > roundEnd {
+				to = roundEnd
+			}
+
+			wp.AddTask(func(ctx context.Context) error {
+				count, err := l.discoverPairs(ctx, factory, from, to)
+				if err != nil {
+					return errors.Wrap(err, "failed to discover pairs", logan.F{
+						"from": from,
+						"to":   to,
+					})
+				}
+
+				atomic.AddInt64(&registered, int64(count))
+
+				return nil
+			})
+		}
+
+		if err := wp.Run(ctx); err != nil {
+			return errors.Wrap(err, "failed to discover pairs", logan.F{
+				"from": round,
+				"to":   roundEnd,
+			})
+		}
+
+		if roundEnd > discovered {
+			if err := factory.SetDiscoveredPairs(ctx, roundEnd); err != nil {
+				return errors.Wrap(err, "failed to save discovery progress")
+			}
+
+			discovered = roundEnd
+		}
+
+		logger.WithFields(logan.F{
+			"discovered": roundEnd,
+			"pairs":      length,
+		}).Debug("pairs discovery progress")
+	}
+
+	logger.WithField("registered", registered).Info("pairs discovery finished")
+
+	return nil
+}
+
+// discoverPairs - registers pairs with indexes in [from, to),
+// returns number of registered ones
+func (l *Listener) discoverPairs(
+	ctx context.Context, factory *contracts.UniswapV2Factory, from, to uint64,
+) (int, error) {
+	pairs, err := factory.AllPairsRange(ctx, from, to)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get pairs")
+	}
+
+	return l.registerPairs(ctx, pairs)
+}
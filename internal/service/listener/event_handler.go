@@ -10,9 +10,8 @@ import (
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
-	uniswapv2factory "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-factory"
-	uniswapv2pair "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-pair"
 	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
 )
 
 type EventHandler func(ctx context.Context, log *types.Log) error
@@ -30,36 +29,39 @@ func (l *Listener) initHandlers(pair, factory abi.ABI) {
 }
 
 func (l *Listener) handleEvent(ctx context.Context, log *types.Log) error {
-	if err := l.currentBlock.UpdateBlock(ctx, log.BlockNumber); err != nil {
-		return errors.Wrap(err, "failed to update current block number")
+	// logs are filtered only by topics, so pairs created
+	// after subscription are listened without resubscribing
+	if !l.isKnownAddress(log.Address) {
+		return nil
 	}
 
-	for _, topic := range log.Topics {
-		handler, ok := l.eventHandlers[topic]
-		if !ok {
-			l.logger.
-				WithField("topic", topic).
-				Warn("unknown topic")
-			continue
-		}
-
-		if err := handler(ctx, log); err != nil {
-			return errors.Wrap(err, "failed to handle log")
-		}
+	// the first topic is event ID, the rest are indexed arguments
+	if len(log.Topics) == 0 {
+		return nil
 	}
-	return nil
+
+	handler, ok := l.eventHandlers[log.Topics[0]]
+	if !ok {
+		l.logger.
+			WithField("topic", log.Topics[0]).
+			Warn("unknown topic")
+		return nil
+	}
+
+	return errors.Wrap(handler(ctx, log), "failed to handle log", logan.F{
+		"tx_hash":   log.TxHash.Hex(),
+		"log_index": log.Index,
+	})
 }
 
 func (l *Listener) handleSwap(ctx context.Context, log *types.Log) error {
-	var event uniswapv2pair.UniswapV2PairSwap
-
-	err := l.eventUnpacker.Unpack(&event, SwapEvent, log.Data)
+	event, err := l.eventUnpacker.pair.ParseSwap(*log)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpack log")
 	}
 
 	l.logger.WithFields(logan.F{
-		"pair":       event.Raw.Address,
+		"pair":       log.Address,
 		"sender":     event.Sender.Hex(),
 		"amount0In":  event.Amount0In.String(),
 		"amount1In":  event.Amount1In.String(),
@@ -68,52 +70,46 @@ func (l *Listener) handleSwap(ctx context.Context, log *types.Log) error {
 		"to":         event.To.Hex(),
 	}).Debug("pair swap")
 
-	token0, token1, err := l.getTokens(ctx, event.Raw.Address)
-	if err != nil {
-		return errors.Wrap(err, "failed get tokens")
-	}
-
 	err = l.eventQueue.Send(ctx, channels.Event{
-		Type: channels.ReservesUpdateEvent,
-		ReservesUpdate: &channels.ReservesUpdate{
-			Address: event.Raw.Address,
-			Token0:  token0,
-			Token1:  token1,
-			// FIXME:
-			Reserve0Delta: &big.Int{},
-			Reserve1Delta: &big.Int{},
+		Type: channels.PairActionEvent,
+		PairAction: &channels.PairAction{
+			Position:   logPosition(log),
+			Type:       channels.SwapAction,
+			Address:    log.Address,
+			Sender:     event.Sender,
+			To:         event.To,
+			Amount0In:  event.Amount0In,
+			Amount1In:  event.Amount1In,
+			Amount0Out: event.Amount0Out,
+			Amount1Out: event.Amount1Out,
 		},
 	})
 	return errors.Wrap(err, "failed to add event to queue")
 }
 
 func (l *Listener) handleSync(ctx context.Context, log *types.Log) error {
-	var event uniswapv2pair.UniswapV2PairSync
-
-	err := l.eventUnpacker.Unpack(&event, SyncEvent, log.Data)
+	event, err := l.eventUnpacker.pair.ParseSync(*log)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpack log")
 	}
 
 	l.logger.WithFields(logan.F{
-		"pair":     event.Raw.Address,
+		"pair":     log.Address,
 		"reserve0": event.Reserve0.String(),
 		"reserve1": event.Reserve1.String(),
 	}).Debug("pair sync")
 
-	token0, token1, err := l.getTokens(ctx, event.Raw.Address)
-	if err != nil {
-		return errors.Wrap(err, "failed get tokens")
+	if pair, ok := l.createdPairs[log.Address]; ok {
+		return l.sendCreatedPair(ctx, log, pair, event.Reserve0, event.Reserve1)
 	}
 
 	err = l.eventQueue.Send(ctx, channels.Event{
 		Type: channels.ReservesUpdateEvent,
 		ReservesUpdate: &channels.ReservesUpdate{
-			Address:       event.Raw.Address,
-			Token0:        token0,
-			Token1:        token1,
-			Reserve0Delta: event.Reserve0,
-			Reserve1Delta: event.Reserve1,
+			Position: logPosition(log),
+			Address:  log.Address,
+			Reserve0: event.Reserve0,
+			Reserve1: event.Reserve1,
 		},
 	})
 
@@ -121,33 +117,29 @@ func (l *Listener) handleSync(ctx context.Context, log *types.Log) error {
 }
 
 func (l *Listener) handleMint(ctx context.Context, log *types.Log) error {
-	var event uniswapv2pair.UniswapV2PairMint
-
-	err := l.eventUnpacker.Unpack(&event, MintEvent, log.Data)
+	event, err := l.eventUnpacker.pair.ParseMint(*log)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpack log")
 	}
 
 	l.logger.WithFields(logan.F{
-		"pair":    event.Raw.Address,
+		"pair":    log.Address,
 		"sender":  event.Sender.Hex(),
 		"amount0": event.Amount0.String(),
 		"amount1": event.Amount1.String(),
 	}).Debug("pair mint")
 
-	token0, token1, err := l.getTokens(ctx, event.Raw.Address)
-	if err != nil {
-		return errors.Wrap(err, "failed get tokens")
-	}
-
 	err = l.eventQueue.Send(ctx, channels.Event{
-		Type: channels.ReservesUpdateEvent,
-		ReservesUpdate: &channels.ReservesUpdate{
-			Address:       event.Raw.Address,
-			Token0:        token0,
-			Token1:        token1,
-			Reserve0Delta: event.Amount0,
-			Reserve1Delta: event.Amount1,
+		Type: channels.PairActionEvent,
+		PairAction: &channels.PairAction{
+			Position:   logPosition(log),
+			Type:       channels.MintAction,
+			Address:    log.Address,
+			Sender:     event.Sender,
+			Amount0In:  event.Amount0,
+			Amount1In:  event.Amount1,
+			Amount0Out: big.NewInt(0),
+			Amount1Out: big.NewInt(0),
 		},
 	})
 
@@ -155,102 +147,126 @@ func (l *Listener) handleMint(ctx context.Context, log *types.Log) error {
 }
 
 func (l *Listener) handleBurn(ctx context.Context, log *types.Log) error {
-	var event uniswapv2pair.UniswapV2PairBurn
-
-	err := l.eventUnpacker.Unpack(&event, BurnEvent, log.Data)
+	event, err := l.eventUnpacker.pair.ParseBurn(*log)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpack log")
 	}
 
 	l.logger.WithFields(logan.F{
+		"pair":    log.Address,
 		"sender":  event.Sender.Hex(),
 		"amount0": event.Amount0.String(),
 		"amount1": event.Amount1.String(),
 		"to":      event.To.Hex(),
 	}).Debug("pair burn")
 
-	token0, token1, err := l.getTokens(ctx, event.Raw.Address)
-	if err != nil {
-		return errors.Wrap(err, "failed get tokens")
-	}
-
 	err = l.eventQueue.Send(ctx, channels.Event{
-		Type: channels.ReservesUpdateEvent,
-		ReservesUpdate: &channels.ReservesUpdate{
-			Address:       event.Raw.Address,
-			Token0:        token0,
-			Token1:        token1,
-			Reserve0Delta: event.Amount0,
-			Reserve1Delta: event.Amount1,
+		Type: channels.PairActionEvent,
+		PairAction: &channels.PairAction{
+			Position:   logPosition(log),
+			Type:       channels.BurnAction,
+			Address:    log.Address,
+			Sender:     event.Sender,
+			To:         event.To,
+			Amount0In:  big.NewInt(0),
+			Amount1In:  big.NewInt(0),
+			Amount0Out: event.Amount0,
+			Amount1Out: event.Amount1,
 		},
 	})
 
 	return errors.Wrap(err, "failed to add event to queue")
 }
 
+// handlePairCreation - registers pair created by known factory, so its
+// logs are not filtered out anymore. Pair has no reserves until its
+// first Sync log, that follows in the same or later transactions, so
+// it is sent to indexer with the first Sync that passes reserves filter.
 func (l *Listener) handlePairCreation(ctx context.Context, log *types.Log) error {
-	var event uniswapv2factory.UniswapV2FactoryPairCreated
-
-	err := l.eventUnpacker.Unpack(&event, PairCreatedEvent, log.Data)
+	// tokens are indexed, so there is no need to request them
+	event, err := l.eventUnpacker.factory.ParsePairCreated(*log)
 	if err != nil {
 		return errors.Wrap(err, "failed to unpack log")
 	}
 
 	l.logger.WithFields(logan.F{
-		"pair":   event.Pair,
-		"token0": event.Token0,
-		"token1": event.Token1,
+		"factory": log.Address,
+		"pair":    event.Pair,
 	}).Debug("pair created")
 
-	token0, token1, err := l.getTokens(ctx, event.Raw.Address)
-	if err != nil {
-		return errors.Wrap(err, "failed get tokens")
+	// pair was restored from snapshot or discovered
+	if l.uniswapV2.Pairs.Get(event.Pair) != nil {
+		return nil
+	}
+
+	factory := l.uniswapV2.Factory(log.Address)
+	if factory == nil {
+		return errors.From(errors.New("unknown factory"), logan.F{
+			"factory": log.Address,
+		})
 	}
 
-	pair := l.uniswapV2.Pairs.Get(event.Raw.Address)
-	reserve0, reserve1, err := pair.GetReserves(ctx)
+	if !l.isPairAllowed(event.Token0, event.Token1) {
+		return nil
+	}
+
+	pair, err := factory.NewPair(event.Pair, event.Token0, event.Token1)
 	if err != nil {
-		return errors.Wrap(err, "failed to get reserves", logan.F{
-			"pair": pair.Address,
+		return errors.Wrap(err, "failed to create pair", logan.F{
+			"pair": event.Pair,
 		})
 	}
 
-	err = l.eventQueue.Send(ctx, channels.Event{
-		Type: channels.PairCreationEvent,
-		PairCreation: &channels.PairCreation{
-			Address:  event.Pair,
-			Token0:   token0,
-			Token1:   token1,
-			Reserve0: reserve0,
-			Reserve1: reserve1,
-		},
-	})
+	l.uniswapV2.Pairs.Set(pair.Address, pair)
+
+	l.createdPairs[pair.Address] = &channels.PairCreation{
+		Address: pair.Address,
+		Factory: factory.Address,
+		Fee:     factory.Fee,
+		Token0:  event.Token0,
+		Token1:  event.Token1,
+	}
 
-	return errors.Wrap(err, "failed to sent pair creation event")
+	return nil
 }
 
-func (l *Listener) getTokens(
-	ctx context.Context, pairAddress common.Address,
-) (token0, token1 common.Address, err error) {
+// sendCreatedPair - sends pair created by log to indexer with reserves
+// of the Sync log, if they pass discovery filter. Otherwise pair waits
+// for the next Sync, as liquidity is usually added after creation.
+func (l *Listener) sendCreatedPair(
+	ctx context.Context, log *types.Log, pair *channels.PairCreation, reserve0, reserve1 *big.Int,
+) error {
+	if !l.hasMinReserve(pair.Token0, reserve0) || !l.hasMinReserve(pair.Token1, reserve1) {
+		return nil
+	}
+
+	delete(l.createdPairs, pair.Address)
 
-	pair := l.uniswapV2.Pairs.Get(pairAddress)
+	// Sync log is not sent separately, as it is
+	// applied with the pair at the same position
+	pair.Position = logPosition(log)
+	pair.Reserve0 = reserve0
+	pair.Reserve1 = reserve1
 
-	token0Contract, err := pair.Token0(ctx)
-	if err != nil {
-		return common.Address{}, common.Address{}, errors.Wrap(err,
-			"failed to get token0",
-			logan.F{
-				"pair": pairAddress.Hex(),
-			})
-	}
-	token1Contract, err := pair.Token1(ctx)
+	err := l.eventQueue.Send(ctx, channels.Event{
+		Type:         channels.PairCreationEvent,
+		PairCreation: pair,
+	})
 	if err != nil {
-		return common.Address{}, common.Address{}, errors.Wrap(err,
-			"failed to get token1",
-			logan.F{
-				"pair": pairAddress.Hex(),
-			})
+		return errors.Wrap(err, "failed to send pair creation event")
 	}
 
-	return token0Contract.Address(), token1Contract.Address(), nil
+	l.archivePairRegistration(pair)
+
+	return nil
+}
+
+func logPosition(log *types.Log) data.LogPosition {
+	return data.LogPosition{
+		BlockNumber: log.BlockNumber,
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.Index,
+		BlockHash:   log.BlockHash,
+		TxHash:      log.TxHash,
+	}
 }
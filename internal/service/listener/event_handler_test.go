@@ -0,0 +1,260 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/logarchive"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/math"
+)
+
+var (
+	testFactory = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	weth        = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc        = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	dai         = common.HexToAddress("0x6B175474E89094C44Da98b6bC44eE4Ac1D3F6dE4")
+)
+
+// newTestListener - returns listener of one factory without node, that
+// indexes pairs between tokens, and channel of events it sends
+func newTestListener(
+	t *testing.T, discovery config.DiscoveryCfg, tokens ...common.Address,
+) (*Listener, <-chan channels.Event) {
+	pairABI, factoryABI, err := parseABIs()
+	require.NoError(t, err)
+
+	eventUnpacker, err := NewEventUnpacker()
+	require.NoError(t, err)
+
+	uniswapV2, err := contracts.NewUniswapV2([]contracts.UniswapV2FactoryParams{{
+		Name:    "uniswapv2",
+		Address: testFactory,
+		Fee:     math.DefaultFee,
+	}}, nil, nil, logan.New(), nil, nil, nil)
+	require.NoError(t, err)
+
+	erc20s := make([]*contracts.ERC20, len(tokens))
+	for i, token := range tokens {
+		erc20s[i], err = contracts.NewERC20(contracts.Erc20Config{Address: token})
+		require.NoError(t, err)
+	}
+
+	queue := channels.NewEventChan()
+	events, err := queue.Receive(context.Background())
+	require.NoError(t, err)
+
+	l := &Listener{
+		logger:        logan.New(),
+		uniswapV2:     uniswapV2,
+		tokens:        erc20s,
+		discoveryCfg:  discovery,
+		pairABI:       pairABI,
+		factoryABI:    factoryABI,
+		createdPairs:  make(map[common.Address]*channels.PairCreation),
+		eventQueue:    queue,
+		eventUnpacker: eventUnpacker,
+	}
+	l.initHandlers(pairABI, factoryABI)
+
+	return l, events
+}
+
+func pairCreatedLog(t *testing.T, token0, token1, pair common.Address, block uint64) *types.Log {
+	_, factoryABI, err := parseABIs()
+	require.NoError(t, err)
+
+	event := factoryABI.Events[PairCreatedEvent.String()]
+
+	data, err := event.Inputs.NonIndexed().Pack(pair, big.NewInt(1))
+	require.NoError(t, err)
+
+	return &types.Log{
+		Address:     testFactory,
+		Topics:      []common.Hash{event.ID, token0.Hash(), token1.Hash()},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+func syncLog(t *testing.T, pair common.Address, block uint64, reserve0, reserve1 int64) *types.Log {
+	pairABI, _, err := parseABIs()
+	require.NoError(t, err)
+
+	event := pairABI.Events[SyncEvent.String()]
+
+	data, err := event.Inputs.Pack(big.NewInt(reserve0), big.NewInt(reserve1))
+	require.NoError(t, err)
+
+	return &types.Log{
+		Address:     pair,
+		Topics:      []common.Hash{event.ID},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+// createdPairs - returns pairs of creation events that are already
+// sent to the channel, failing on other events
+func createdPairs(t *testing.T, events <-chan channels.Event) []*channels.PairCreation {
+	pairs := make([]*channels.PairCreation, 0)
+
+	for {
+		select {
+		case event := <-events:
+			require.Equal(t, channels.PairCreationEvent, event.Type)
+			pairs = append(pairs, event.PairCreation)
+		default:
+			return pairs
+		}
+	}
+}
+
+func Test_HandlePairCreation(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		wethUSDC = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		wethDAI  = common.HexToAddress("0x0000000000000000000000000000000000000002")
+	)
+
+	t.Run("pair of configured tokens is sent with the first sync", func(t *testing.T) {
+		l, events := newTestListener(t, config.DiscoveryCfg{}, weth, usdc)
+
+		require.NoError(t, l.handleEvent(ctx, pairCreatedLog(t, weth, usdc, wethUSDC, 10)))
+		require.Empty(t, createdPairs(t, events))
+
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethUSDC, 11, 100, 200)))
+
+		pairs := createdPairs(t, events)
+		require.Len(t, pairs, 1)
+		require.Equal(t, wethUSDC, pairs[0].Address)
+		require.Equal(t, uint64(11), pairs[0].Position.BlockNumber)
+		require.Equal(t, big.NewInt(100), pairs[0].Reserve0)
+		require.Equal(t, big.NewInt(200), pairs[0].Reserve1)
+	})
+
+	t.Run("pair with not configured token is skipped", func(t *testing.T) {
+		l, events := newTestListener(t, config.DiscoveryCfg{}, weth, usdc)
+
+		require.NoError(t, l.handleEvent(ctx, pairCreatedLog(t, weth, dai, wethDAI, 10)))
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethDAI, 11, 100, 200)))
+
+		require.Empty(t, createdPairs(t, events))
+		require.Nil(t, l.uniswapV2.Pairs.Get(wethDAI))
+	})
+
+	t.Run("pair waits for reserves", func(t *testing.T) {
+		l, events := newTestListener(t, config.DiscoveryCfg{
+			MinReserves: map[common.Address]*big.Int{weth: big.NewInt(1000)},
+		}, weth, usdc)
+
+		require.NoError(t, l.handleEvent(ctx, pairCreatedLog(t, weth, usdc, wethUSDC, 10)))
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethUSDC, 11, 999, 200)))
+		require.Empty(t, createdPairs(t, events))
+
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethUSDC, 12, 1000, 200)))
+
+		pairs := createdPairs(t, events)
+		require.Len(t, pairs, 1)
+		require.Equal(t, uint64(12), pairs[0].Position.BlockNumber)
+	})
+
+	t.Run("discovery allowlist", func(t *testing.T) {
+		l, events := newTestListener(t, config.DiscoveryCfg{
+			Enabled:       true,
+			AllowedTokens: []common.Address{weth, dai},
+		})
+
+		require.NoError(t, l.handleEvent(ctx, pairCreatedLog(t, weth, usdc, wethUSDC, 10)))
+		require.NoError(t, l.handleEvent(ctx, pairCreatedLog(t, weth, dai, wethDAI, 10)))
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethUSDC, 11, 100, 200)))
+		require.NoError(t, l.handleEvent(ctx, syncLog(t, wethDAI, 11, 100, 200)))
+
+		pairs := createdPairs(t, events)
+		require.Len(t, pairs, 1)
+		require.Equal(t, wethDAI, pairs[0].Address)
+	})
+}
+
+func Test_ReplayPairRegistrations(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var (
+		wethUSDC = common.HexToAddress("0x0000000000000000000000000000000000000001")
+		wethDAI  = common.HexToAddress("0x0000000000000000000000000000000000000002")
+	)
+
+	live, liveEvents := newTestListener(t, config.DiscoveryCfg{}, weth, usdc, dai)
+
+	archive, err := logarchive.NewWriter(dir, 0)
+	require.NoError(t, err)
+	live.archive = archive
+
+	// pair loaded from node at startup
+	pair, err := live.uniswapV2.Factories[0].NewPair(wethUSDC, weth, usdc)
+	require.NoError(t, err)
+
+	ok, err := live.registerPair(ctx, 5, contracts.PairState{
+		Pair:     pair,
+		Token0:   weth,
+		Token1:   usdc,
+		Reserve0: big.NewInt(100),
+		Reserve1: big.NewInt(200),
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// pair created after startup
+	for _, log := range []*types.Log{
+		pairCreatedLog(t, weth, dai, wethDAI, 10),
+		syncLog(t, wethDAI, 11, 300, 400),
+	} {
+		live.archiveLog(log)
+		require.NoError(t, live.handleEvent(ctx, log))
+	}
+
+	require.NoError(t, archive.Close())
+	require.Len(t, createdPairs(t, liveEvents), 2)
+
+	// replayer has no tokens config, so pairs are registered
+	// only from archived registrations
+	replayer, replayEvents := newTestListener(t, config.DiscoveryCfg{})
+	replayer.currentBlock = providers.NewBlockMemoryProvider()
+	replayer.blocks = newBlocksHistory(0)
+	replayer.eventHandlers[replayer.factoryABI.Events[PairCreatedEvent.String()].ID] = skipLog
+
+	require.NoError(t, replayer.Replay(ctx, dir, nil))
+
+	pairs := make([]*channels.PairCreation, 0)
+	for len(replayEvents) > 0 {
+		event := <-replayEvents
+		if event.Type == channels.PairCreationEvent {
+			pairs = append(pairs, event.PairCreation)
+		}
+	}
+
+	require.Len(t, pairs, 2)
+
+	require.Equal(t, wethUSDC, pairs[0].Address)
+	require.Equal(t, data.EndOfBlock(5), pairs[0].Position)
+	require.Equal(t, big.NewInt(100), pairs[0].Reserve0)
+	require.Equal(t, big.NewInt(200), pairs[0].Reserve1)
+
+	require.Equal(t, wethDAI, pairs[1].Address)
+	require.Equal(t, testFactory, pairs[1].Factory)
+	require.Equal(t, uint64(11), pairs[1].Position.BlockNumber)
+	require.Equal(t, big.NewInt(300), pairs[1].Reserve0)
+	require.Equal(t, big.NewInt(400), pairs[1].Reserve1)
+}
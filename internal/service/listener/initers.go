@@ -2,52 +2,270 @@ package listener
 
 import (
 	"context"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/helpers"
 )
 
-// FIXME: this is a temporary solution, need to find a better way to handle rate
-// limit errors that alchemy makes, when we make too many requests. May be,
-// infura has better solutions for that
-// const errRateLimitStr = "Your app has exceeded its compute units"
+// restoreContracts - registers pairs from the graph snapshot, so
+// there is no need to request their reserves from node again, as
+// indexer restores them from the same snapshot. Logs are continued
+// from the snapshot checkpoint.
+func (l *Listener) restoreContracts(ctx context.Context) error {
+	snapshot, err := l.snapshots.GetSnapshot(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get graph snapshot")
+	}
+
+	if snapshot == nil {
+		return nil
+	}
+
+	if err := l.registerSnapshotPairs(snapshot); err != nil {
+		return errors.Wrap(err, "failed to register pairs")
+	}
+
+	l.snapshotBlock = snapshot.Position.BlockNumber
+	// logs up to the checkpoint are already applied to the graph
+	l.lastLog = snapshot.Position
+
+	// hashes of recent blocks are checked, as they could be
+	// reorganized while listener was down
+	for _, block := range snapshot.Blocks {
+		if !helpers.IsHashZero(block.Hash) {
+			l.blocks.Add(block.Number, block.Hash)
+		}
+	}
+	if !helpers.IsHashZero(snapshot.Position.BlockHash) {
+		l.blocks.Add(snapshot.Position.BlockNumber, snapshot.Position.BlockHash)
+	}
+
+	return nil
+}
+
+// registerSnapshotPairs - registers pairs of graph snapshot
+// without sending them to indexer
+func (l *Listener) registerSnapshotPairs(snapshot *data.GraphSnapshot) error {
+	for _, edge := range snapshot.Edges {
+		factory := l.uniswapV2.Factory(edge.Factory)
+		// snapshot was made before factories were stored,
+		// when only one factory was supported
+		if helpers.IsAddressZero(edge.Factory) {
+			factory = l.uniswapV2.Factories[0]
+		}
+		if factory == nil {
+			l.logger.WithField("pair", edge.Address).Warn("pair factory is not configured")
+			continue
+		}
+
+		pair, err := factory.NewPair(edge.Address, edge.Token0, edge.Token1)
+		if err != nil {
+			return errors.Wrap(err, "failed to create pair", logan.F{
+				"address": edge.Address,
+			})
+		}
+
+		l.uniswapV2.Pairs.Set(pair.Address, pair)
+	}
+
+	return nil
+}
 
 func (l *Listener) initContracts(ctx context.Context) error {
+	for _, factory := range l.uniswapV2.Factories {
+		initFactory := l.initFactoryContracts
+		if l.discoveryCfg.Enabled {
+			initFactory = l.discoverFactoryContracts
+		}
+
+		if err := initFactory(ctx, factory); err != nil {
+			return errors.Wrap(err, "failed to init factory contracts", logan.F{
+				"factory": factory.Name,
+			})
+		}
+	}
+
+	return nil
+}
+
+// initFactoryContracts - registers pairs of factory between
+// configured tokens
+func (l *Listener) initFactoryContracts(ctx context.Context, factory *contracts.UniswapV2Factory) error {
+	tokens := make([][2]common.Address, 0, len(l.tokens)*len(l.tokens)/2)
 	for i, token0 := range l.tokens {
 		for _, token1 := range l.tokens[i+1:] {
-			pair, err := l.uniswapV2.Factory.GetPool(ctx, token0.Address(), token1.Address())
-			if err != nil {
-				return errors.Wrap(err, "failed to get pair address")
-			}
-
-			l.uniswapV2.Pairs.Set(pair.Address, pair)
-
-			reserve0, reserve1, err := pair.GetReserves(ctx)
-			if err != nil {
-				return errors.Wrap(err, "failed to get reserves", logan.F{
-					"address": pair.Address,
-				})
-			}
-
-			err = l.eventQueue.Send(ctx, channels.Event{
-				Type: channels.PairCreationEvent,
-				PairCreation: &channels.PairCreation{
-					Address:  pair.Address,
-					Reserve0: reserve0,
-					Reserve1: reserve1,
-				},
-			})
-			if err != nil {
-				return errors.Wrap(err, "failed to send pair creation event",
-					logan.F{
-						"reserve0": reserve0.String(),
-						"reserve1": reserve1.String(),
-						"address":  pair.Address,
-					})
-			}
+			tokens = append(tokens, [2]common.Address{token0.Address(), token1.Address()})
 		}
 	}
+
+	pairs, err := factory.GetPools(ctx, tokens)
+	if err != nil {
+		return errors.Wrap(err, "failed to get pairs addresses")
+	}
+
+	if _, err := l.registerPairs(ctx, pairs); err != nil {
+		return errors.Wrap(err, "failed to register pairs")
+	}
+
 	return nil
 }
+
+// registerPairs - loads tokens and reserves of pairs at the same block
+// and sends them to indexer. Pairs that are already registered, are not
+// deployed or are filtered out by discovery settings are skipped, as well
+// as pairs which calls failed. Returns number of registered pairs.
+func (l *Listener) registerPairs(ctx context.Context, pairs []*contracts.UniswapV2Pair) (int, error) {
+	unknown := make([]*contracts.UniswapV2Pair, 0, len(pairs))
+	for _, pair := range pairs {
+		// there is no such pair in factory
+		if helpers.IsAddressZero(pair.Address) {
+			continue
+		}
+
+		// pair was restored from snapshot
+		if l.uniswapV2.Pairs.Get(pair.Address) != nil {
+			continue
+		}
+
+		unknown = append(unknown, pair)
+	}
+
+	if len(unknown) == 0 {
+		return 0, nil
+	}
+
+	block, states, err := l.uniswapV2.LoadPairs(ctx, unknown)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to load pairs")
+	}
+
+	var registered int
+	for _, state := range states {
+		// address of the pair between configured tokens is computed,
+		// and most of such pairs are never created
+		if state.Err == contracts.ErrPairNotDeployed {
+			continue
+		}
+
+		if state.Err != nil {
+			l.logger.WithError(state.Err).WithFields(logan.F{
+				"pair":  state.Pair.Address,
+				"block": block,
+			}).Warn("failed to load pair, skipping")
+			continue
+		}
+
+		ok, err := l.registerPair(ctx, block, state)
+		if err != nil {
+			return registered, errors.Wrap(err, "failed to register pair", logan.F{
+				"pair": state.Pair.Address,
+			})
+		}
+
+		if ok {
+			registered++
+		}
+	}
+
+	return registered, nil
+}
+
+// registerPair - sends pair loaded at the block to indexer, so logs
+// of the block and earlier ones don't overwrite its reserves. Returns
+// false if pair is filtered out by discovery settings.
+func (l *Listener) registerPair(ctx context.Context, block uint64, state contracts.PairState) (bool, error) {
+	if !l.isPairAllowed(state.Token0, state.Token1) {
+		return false, nil
+	}
+
+	if !l.hasMinReserve(state.Token0, state.Reserve0) || !l.hasMinReserve(state.Token1, state.Reserve1) {
+		return false, nil
+	}
+
+	pair := state.Pair
+	l.uniswapV2.Pairs.Set(pair.Address, pair)
+
+	creation := &channels.PairCreation{
+		Position: data.EndOfBlock(block),
+		Address:  pair.Address,
+		Factory:  pair.Factory,
+		Fee:      pair.Fee,
+		Token0:   state.Token0,
+		Token1:   state.Token1,
+		Reserve0: state.Reserve0,
+		Reserve1: state.Reserve1,
+	}
+
+	err := l.eventQueue.Send(ctx, channels.Event{
+		Type:         channels.PairCreationEvent,
+		PairCreation: creation,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to send pair creation event",
+			logan.F{
+				"reserve0": state.Reserve0.String(),
+				"reserve1": state.Reserve1.String(),
+				"address":  pair.Address,
+			})
+	}
+
+	l.archivePairRegistration(creation)
+
+	return true, nil
+}
+
+// isPairAllowed - returns true if pair between tokens is indexed: both
+// tokens should be configured if discovery is disabled, or pass discovery
+// allowlist otherwise, where empty allowlist allows every pair
+func (l *Listener) isPairAllowed(token0, token1 common.Address) bool {
+	if !l.discoveryCfg.Enabled {
+		return l.isTokenConfigured(token0) && l.isTokenConfigured(token1)
+	}
+
+	return l.isTokenAllowed(token0) && l.isTokenAllowed(token1)
+}
+
+// isTokenConfigured - returns true if token is one of configured tokens
+func (l *Listener) isTokenConfigured(token common.Address) bool {
+	for _, configured := range l.tokens {
+		if configured.Address() == token {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTokenAllowed - returns true if token passes discovery allowlist,
+// empty allowlist allows every token
+func (l *Listener) isTokenAllowed(token common.Address) bool {
+	if len(l.discoveryCfg.AllowedTokens) == 0 {
+		return true
+	}
+
+	for _, allowed := range l.discoveryCfg.AllowedTokens {
+		if allowed == token {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasMinReserve - returns true if reserve of token is not less than
+// its configured minimum, or if there is no minimum for the token
+func (l *Listener) hasMinReserve(token common.Address, reserve *big.Int) bool {
+	minReserve, ok := l.discoveryCfg.MinReserves[token]
+	if !ok {
+		return true
+	}
+
+	return reserve.Cmp(minReserve) >= 0
+}
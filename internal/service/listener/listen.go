@@ -2,7 +2,7 @@ package listener
 
 import (
 	"context"
-	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -11,29 +11,92 @@ import (
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
-	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
 )
 
+// Listen - loads historical logs if backfill is needed, then
+// subscribes to new ones. If subscription fails, it is recreated
+// with backoff, and logs after the last processed one are loaded
+// with eth_getLogs, so none of them is lost.
 func (l *Listener) Listen(ctx context.Context) error {
-	query, err := l.filters(ctx)
+	query, err := l.filters()
 	if err != nil {
 		return errors.Wrap(err, "failed to set subscription filters")
 	}
 
+	if err := l.checkCheckpoint(ctx); err != nil {
+		return errors.Wrap(err, "failed to check checkpoint")
+	}
+
+	from, backfill, err := l.backfillStart(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get backfill start block")
+	}
+
+	if backfill {
+		if err := l.backfillToHead(ctx, query, from); err != nil {
+			return errors.Wrap(err, "failed to backfill logs")
+		}
+	}
+
+	reconnect := newBackoff(l.ethereumCfg.ReconnectMinDelay, l.ethereumCfg.ReconnectMaxDelay)
+
+	for {
+		err := l.subscribe(ctx, query, reconnect)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		delay := reconnect.Next()
+
+		l.logger.WithError(err).
+			WithField("delay", delay.String()).
+			Warn("logs subscription failed, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// subscribe - subscribes to logs and processes them until subscription
+// fails. Logs that were produced since the last processed one are
+// loaded after subscription is created, and the same logs received
+// from subscription are skipped.
+func (l *Listener) subscribe(ctx context.Context, query ethereum.FilterQuery, reconnect *backoff) error {
 	logs := make(chan types.Log)
-	sub, err := l.client.SubscribeFilterLogs(ctx, query, logs)
+	sub, err := l.logs.SubscribeFilterLogs(ctx, query, logs)
 	if err != nil {
 		return errors.Wrap(err, "failed to subscribe to logs")
 	}
 	defer sub.Unsubscribe()
 
+	// there is nothing to continue from on the first run without backfill
+	if !l.lastLog.IsZero() {
+		if err := l.backfillToHead(ctx, query, l.lastLog.BlockNumber); err != nil {
+			return errors.Wrap(err, "failed to fill logs gap")
+		}
+	}
+
+	reconnect.Reset()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case err := <-sub.Err():
-			return errors.Wrap(err, "failed to subscribe to logs")
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return errors.Wrap(err, "logs subscription failed")
 		case vLog := <-logs:
+			// removed logs are not skipped, as they
+			// could revert already processed blocks
+			if !vLog.Removed && !logPosition(&vLog).After(l.lastLog) {
+				continue
+			}
+
 			if err := l.proccessLog(ctx, &vLog); err != nil {
 				l.logger.WithError(err).Error("failed to handle event")
 			}
@@ -41,7 +104,33 @@ func (l *Listener) Listen(ctx context.Context) error {
 	}
 }
 
+// proccessLog - applies log received from subscription or backfill,
+// checking that chain was not reorganized before it
 func (l *Listener) proccessLog(ctx context.Context, log *types.Log) error {
+	l.archiveLog(log)
+
+	if log.Removed {
+		return errors.Wrap(l.handleRemovedLog(ctx, log), "failed to handle removed log")
+	}
+
+	if err := l.checkReorg(ctx, log); err != nil {
+		return errors.Wrap(err, "failed to check reorg")
+	}
+
+	return l.applyLog(ctx, log)
+}
+
+// applyLog - sends events of the log, and of the new block
+// if log is the first one in it
+func (l *Listener) applyLog(ctx context.Context, log *types.Log) error {
+	l.blocks.Add(log.BlockNumber, log.BlockHash)
+	l.lastLog = logPosition(log)
+	// state of pairs is read from nodes that have the block of
+	// log, there is no client if logs are replayed from archive
+	if l.client != nil {
+		l.client.SeenBlock(log.BlockNumber)
+	}
+
 	block, err := l.currentBlock.CurrentBlock(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to get current block")
@@ -64,52 +153,60 @@ func (l *Listener) proccessLog(ctx context.Context, log *types.Log) error {
 	}
 
 	if err := l.handleEvent(ctx, log); err != nil {
-		return errors.Wrap(err, "failed to update current block")
+		return errors.Wrap(err, "failed to handle event")
 	}
 	return nil
 }
 
-func (l *Listener) filters(ctx context.Context) (ethereum.FilterQuery, error) {
-	addresses := l.getAllPairsAddresses()
+// archiveLog - writes received log to archive if it is enabled,
+// failure to write it doesn't stop processing
+func (l *Listener) archiveLog(log *types.Log) {
+	if l.archive == nil {
+		return
+	}
 
-	block, err := l.currentBlock.CurrentBlock(ctx)
-	if err != nil {
-		return ethereum.FilterQuery{}, errors.Wrap(err,
-			"failed to get last block",
-		)
+	if err := l.archive.Write(log); err != nil {
+		l.logger.WithError(err).Error("failed to archive log")
 	}
+}
 
-	var blockInt *big.Int = nil
-	// that means, that we weren't initialized yet,
-	// and we need to start indexing from last block
-	if block != 0 {
-		// other wise, start indexing from last saved block
-		blockInt = new(big.Int).SetUint64(block)
+// backfillToHead - loads logs from block to current head
+func (l *Listener) backfillToHead(
+	ctx context.Context, query ethereum.FilterQuery, from uint64,
+) error {
+	head, err := l.client.BlockNumber(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get head block number")
 	}
 
+	return l.backfill(ctx, query, from, head)
+}
+
+// filters - returns query for all configured events without blocks
+// range. Query is not limited by addresses, as the set of pairs grows
+// with every PairCreated log and could be too large for node, so logs
+// of unknown contracts are filtered out by listener.
+func (l *Listener) filters() (ethereum.FilterQuery, error) {
 	topics := make([]common.Hash, 0)
 	for _, event := range AllEvents() {
-		topic, ok := l.pairABI.Events[string(event)]
+		// PairCreated is the only event of factory
+		abi := l.pairABI
+		if event == PairCreatedEvent {
+			abi = l.factoryABI
+		}
+
+		topic, ok := abi.Events[string(event)]
 		if !ok {
-			return ethereum.FilterQuery{}, errors.Wrap(err,
-				"no such event in abi",
+			return ethereum.FilterQuery{}, errors.From(
+				errors.New("no such event in abi"),
 				logan.F{
 					"event": event,
 				})
 		}
 		topics = append(topics, topic.ID)
 	}
-	topic, ok := l.factoryABI.Events["PairCreated"]
-	if !ok {
-		return ethereum.FilterQuery{}, errors.Wrap(err,
-			"no such event in factory abi",
-		)
-	}
-	topics = append(topics, topic.ID)
 
 	query := ethereum.FilterQuery{
-		FromBlock: blockInt,
-		Addresses: addresses,
 		Topics: [][]common.Hash{
 			topics,
 		},
@@ -118,13 +215,12 @@ func (l *Listener) filters(ctx context.Context) (ethereum.FilterQuery, error) {
 	return query, nil
 }
 
-func (l *Listener) getAllPairsAddresses() []common.Address {
-	var addresses []common.Address
-
-	l.uniswapV2.Pairs.Range(func(addr common.Address, _ *contracts.UniswapV2Pair) bool {
-		addresses = append(addresses, addr)
+// isKnownAddress - returns true if address is one of configured
+// factories or registered pairs
+func (l *Listener) isKnownAddress(address common.Address) bool {
+	if l.uniswapV2.Factory(address) != nil {
 		return true
-	})
+	}
 
-	return addresses
+	return l.uniswapV2.Pairs.Get(address) != nil
 }
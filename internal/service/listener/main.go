@@ -4,9 +4,9 @@ import (
 	"context"
 	"strings"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
@@ -15,20 +15,50 @@ import (
 	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
 	"github.com/Velnbur/uniswapv2-indexer/internal/config"
 	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
 	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/logarchive"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
 )
 
 type Listener struct {
-	client *ethclient.Client
+	client *multiclient.Client
+	// logs - subscription to logs with websocket node, or its
+	// polling implementation for HTTP one
+	logs   ethereum.LogFilterer
 	logger *logan.Entry
 
 	pairABI    abi.ABI
 	factoryABI abi.ABI
 
 	uniswapV2 *contracts.UniswapV2
-	tokens    []*contracts.ERC20
+	// tokens - pairs between them are indexed if discovery is disabled
+	tokens       []*contracts.ERC20
+	discoveryCfg config.DiscoveryCfg
+	// createdPairs - pairs registered by PairCreated logs, that are
+	// not sent to indexer until they get reserves that pass filter
+	createdPairs map[common.Address]*channels.PairCreation
 
 	currentBlock providers.CurrentBlockProvider
+	// confirmations - queue that delays events until their blocks are
+	// confirmed, nil if events are sent to indexer immediately
+	confirmations *confirmedQueue
+	// confirmedBlock - the last block which events were sent to indexer
+	// after confirmation, nil if confirmations are disabled
+	confirmedBlock providers.CurrentBlockProvider
+	snapshots      providers.GraphSnapshotProvider
+	// snapshotBlock - block of the graph snapshot indexer was restored
+	// from, logs are replayed starting from it
+	snapshotBlock uint64
+	backfillCfg   config.BackfillCfg
+	ethereumCfg   config.EthereumCfg
+	// lastLog - position of the last processed log, subscription
+	// is continued from it after reconnection
+	lastLog data.LogPosition
+	// blocks - recent blocks, that are checked for reorgs
+	blocks *blocksHistory
+	// archive - writer of received logs, nil if archive is disabled
+	archive *logarchive.Writer
 
 	eventQueue    channels.EventQueue
 	eventHandlers map[common.Hash]EventHandler
@@ -36,29 +66,86 @@ type Listener struct {
 }
 
 func NewListener(cfg config.Config) (*Listener, error) {
-	pairABI, err := abi.JSON(strings.NewReader(
-		string(uniswapv2pair.UniswapV2PairABI),
-	))
+	pairABI, factoryABI, err := parseABIs()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse pair ABI")
+		return nil, errors.Wrap(err, "failed to parse ABIs")
 	}
 
-	factoryABI, err := abi.JSON(strings.NewReader(
-		string(uniswapv2factory.UniswapV2FactoryABI),
-	))
+	eventUnpacker, err := NewEventUnpacker()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse factory ABI")
+		return nil, errors.Wrap(err, "failed to create event unpacker")
+	}
+
+	logger := cfg.Log().WithField("service", "listener")
+
+	uniswapV2, err := contracts.NewUniswapV2(
+		cfg.ContracterCfg().Factories, cfg.EthereumClient(), cfg.Multicall(), logger,
+		providers.NewUniswapV2FactoryRedisProvider(cfg.Redis()),
+		providers.NewUniswapV2PairsRedisProvider(cfg.Redis()),
+		providers.NewErc20RedisProvider(cfg.Redis()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create uniswapv2 contracts")
+	}
+
+	var logs ethereum.LogFilterer = cfg.EthereumClient()
+	if !cfg.EthereumClient().SupportsSubscriptions() {
+		interval := cfg.EthereumCfg().PollInterval
+
+		logger.WithField("interval", interval.String()).
+			Info("nodes don't support subscriptions, logs are polled")
+
+		logs = newLogsPoller(cfg.EthereumClient(), interval)
+	}
+
+	var (
+		confirmations  *confirmedQueue
+		confirmedBlock providers.CurrentBlockProvider
+	)
+
+	eventQueue := cfg.EventsQueue()
+	if confirmationsCfg := cfg.ConfirmationsCfg(); confirmationsCfg.Enabled() || confirmationsCfg.Pending {
+		var pending channels.EventQueue
+		if confirmationsCfg.Pending {
+			pending = cfg.PendingEventsQueue()
+		}
+
+		confirmedBlock = providers.NewConfirmedBlockProvider(cfg.Redis())
+		confirmations = newConfirmedQueue(
+			eventQueue, pending, cfg.EthereumClient(),
+			confirmationsCfg, confirmedBlock, logger,
+		)
+		eventQueue = confirmations
+	}
+
+	var archive *logarchive.Writer
+	if archiveCfg := cfg.ArchiveCfg(); archiveCfg.Enabled {
+		archive, err = logarchive.NewWriter(archiveCfg.Dir, archiveCfg.SegmentSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open logs archive")
+		}
 	}
 
 	listener := &Listener{
-		client:        cfg.EthereumClient(),
-		logger:        cfg.Log().WithField("service", "listener"),
-		pairABI:       pairABI,
-		factoryABI:    factoryABI,
-		tokens:        cfg.Tokens(),
-		currentBlock:  providers.NewBlockProvider(cfg.Redis()),
-		eventQueue:    channels.NewEventChan(),
-		eventUnpacker: NewEventUnpacker(&pairABI, &factoryABI),
+		client:         cfg.EthereumClient(),
+		logs:           logs,
+		logger:         logger,
+		pairABI:        pairABI,
+		factoryABI:     factoryABI,
+		uniswapV2:      uniswapV2,
+		tokens:         cfg.Tokens(),
+		discoveryCfg:   cfg.DiscoveryCfg(),
+		createdPairs:   make(map[common.Address]*channels.PairCreation),
+		currentBlock:   providers.NewBlockProvider(cfg.Redis()),
+		confirmations:  confirmations,
+		confirmedBlock: confirmedBlock,
+		snapshots:      providers.NewGraphSnapshotRedisProvider(cfg.Redis()),
+		backfillCfg:    cfg.BackfillCfg(),
+		ethereumCfg:    cfg.EthereumCfg(),
+		blocks:         newBlocksHistory(cfg.EthereumCfg().ReorgDepth),
+		archive:        archive,
+		eventQueue:     eventQueue,
+		eventUnpacker:  eventUnpacker,
 	}
 	listener.initHandlers(pairABI, factoryABI)
 
@@ -66,9 +153,44 @@ func NewListener(cfg config.Config) (*Listener, error) {
 }
 
 func (l *Listener) Run(ctx context.Context) error {
+	if l.archive != nil {
+		defer func() {
+			if err := l.archive.Close(); err != nil {
+				l.logger.WithError(err).Error("failed to close logs archive")
+			}
+		}()
+	}
+
+	if err := l.restoreContracts(ctx); err != nil {
+		return errors.Wrap(err, "failed to restore contracts from snapshot")
+	}
+
 	if err := l.initContracts(ctx); err != nil {
 		return errors.Wrap(err, "failed to init contracts")
 	}
 
+	// new blocks are expected at least as often as logs are polled
+	if l.confirmations != nil {
+		go l.confirmations.Watch(ctx, l.ethereumCfg.PollInterval)
+	}
+
 	return l.Listen(ctx)
 }
+
+func parseABIs() (pair, factory abi.ABI, err error) {
+	pair, err = abi.JSON(strings.NewReader(
+		string(uniswapv2pair.UniswapV2PairABI),
+	))
+	if err != nil {
+		return abi.ABI{}, abi.ABI{}, errors.Wrap(err, "failed to parse pair ABI")
+	}
+
+	factory, err = abi.JSON(strings.NewReader(
+		string(uniswapv2factory.UniswapV2FactoryABI),
+	))
+	if err != nil {
+		return abi.ABI{}, abi.ABI{}, errors.Wrap(err, "failed to parse factory ABI")
+	}
+
+	return pair, factory, nil
+}
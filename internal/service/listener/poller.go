@@ -0,0 +1,95 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/pkg/multiclient"
+)
+
+var _ ethereum.LogFilterer = &logsPoller{}
+
+// logsPoller - implements logs subscription with eth_blockNumber and
+// eth_getLogs requests made periodically, for nodes that are accessible
+// only over HTTP and don't support eth_subscribe. Logs are delivered
+// in the same order as with subscription, but without removed ones,
+// so reorgs are detected by blocks hashes only.
+type logsPoller struct {
+	client   *multiclient.Client
+	interval time.Duration
+}
+
+func newLogsPoller(client *multiclient.Client, interval time.Duration) *logsPoller {
+	return &logsPoller{
+		client:   client,
+		interval: interval,
+	}
+}
+
+func (p *logsPoller) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return p.client.FilterLogs(ctx, query)
+}
+
+// SubscribeFilterLogs - sends logs of blocks that appear after the
+// subscription is created. Subscription fails on the first error of
+// node, so listener could reconnect and fill the gap as usual.
+func (p *logsPoller) SubscribeFilterLogs(
+	ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	head, err := p.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get head block number")
+	}
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+
+			latest, err := p.client.BlockNumber(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to get head block number")
+			}
+
+			if latest <= head {
+				continue
+			}
+
+			query.FromBlock = new(big.Int).SetUint64(head + 1)
+			query.ToBlock = new(big.Int).SetUint64(latest)
+
+			logs, err := p.client.FilterLogs(ctx, query)
+			if err != nil {
+				return errors.Wrap(err, "failed to filter logs", logan.F{
+					"from": head + 1,
+					"to":   latest,
+				})
+			}
+
+			sortLogs(logs)
+
+			for _, log := range logs {
+				select {
+				case <-quit:
+					return nil
+				case ch <- log:
+				}
+			}
+
+			head = latest
+		}
+	}), nil
+}
@@ -0,0 +1,124 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+)
+
+// Pairs loaded from node, as well as created pairs that passed filters,
+// are not logs, so they are archived as synthetic logs of the pair.
+// Replay registers pairs only from them, so it doesn't need node to load
+// pairs and tokens config to make the same decisions.
+
+// pairRegisteredTopic - ID of synthetic event, which is not emitted
+// by any contract
+var pairRegisteredTopic = crypto.Keccak256Hash(
+	[]byte("PairRegistered(address,address,address,uint256,uint256)"),
+)
+
+var (
+	addressType, _ = abi.NewType("address", "", nil)
+	uint256Type, _ = abi.NewType("uint256", "", nil)
+
+	// pairRegisteredArgs - factory, tokens and reserves of the pair
+	pairRegisteredArgs = abi.Arguments{
+		{Name: "factory", Type: addressType},
+		{Name: "token0", Type: addressType},
+		{Name: "token1", Type: addressType},
+		{Name: "reserve0", Type: uint256Type},
+		{Name: "reserve1", Type: uint256Type},
+	}
+)
+
+func isPairRegistration(log *types.Log) bool {
+	return len(log.Topics) > 0 && log.Topics[0] == pairRegisteredTopic
+}
+
+// archivePairRegistration - writes pair sent to indexer to archive
+// if it is enabled, failure to write it doesn't stop processing
+func (l *Listener) archivePairRegistration(pair *channels.PairCreation) {
+	if l.archive == nil {
+		return
+	}
+
+	raw, err := pairRegisteredArgs.Pack(pair.Factory, pair.Token0, pair.Token1, pair.Reserve0, pair.Reserve1)
+	if err != nil {
+		l.logger.WithError(err).WithField("pair", pair.Address).Error("failed to pack pair registration")
+		return
+	}
+
+	err = l.archive.Write(&types.Log{
+		Address:     pair.Address,
+		Topics:      []common.Hash{pairRegisteredTopic},
+		Data:        raw,
+		BlockNumber: pair.Position.BlockNumber,
+		TxHash:      pair.Position.TxHash,
+		TxIndex:     pair.Position.TxIndex,
+		BlockHash:   pair.Position.BlockHash,
+		Index:       pair.Position.LogIndex,
+	})
+	if err != nil {
+		l.logger.WithError(err).WithField("pair", pair.Address).Error("failed to archive pair registration")
+	}
+}
+
+// replayPairRegistration - registers pair from archive and sends it to
+// indexer at the same position, as listener did
+func (l *Listener) replayPairRegistration(ctx context.Context, log *types.Log) error {
+	values, err := pairRegisteredArgs.Unpack(log.Data)
+	if err != nil {
+		return errors.Wrap(err, "failed to unpack pair registration")
+	}
+
+	var (
+		factoryAddress = values[0].(common.Address)
+		token0         = values[1].(common.Address)
+		token1         = values[2].(common.Address)
+	)
+
+	// pair was restored from snapshot
+	if l.uniswapV2.Pairs.Get(log.Address) != nil {
+		return nil
+	}
+
+	factory := l.uniswapV2.Factory(factoryAddress)
+	if factory == nil {
+		return errors.From(errors.New("unknown factory"), logan.F{
+			"factory": factoryAddress,
+		})
+	}
+
+	pair, err := factory.NewPair(log.Address, token0, token1)
+	if err != nil {
+		return errors.Wrap(err, "failed to create pair", logan.F{
+			"pair": log.Address,
+		})
+	}
+
+	l.uniswapV2.Pairs.Set(pair.Address, pair)
+
+	err = l.eventQueue.Send(ctx, channels.Event{
+		Type: channels.PairCreationEvent,
+		PairCreation: &channels.PairCreation{
+			Position: logPosition(log),
+			Address:  pair.Address,
+			Factory:  factory.Address,
+			Fee:      factory.Fee,
+			Token0:   token0,
+			Token1:   token1,
+			Reserve0: values[3].(*big.Int),
+			Reserve1: values[4].(*big.Int),
+		},
+	})
+
+	return errors.Wrap(err, "failed to send pair creation event")
+}
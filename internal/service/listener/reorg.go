@@ -0,0 +1,188 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+)
+
+// handleRemovedLog - rolls back state to the block before the block of
+// removed log. Node sends removed logs of the old chain before the logs
+// of the new one, so canonical logs are applied after it as usual.
+func (l *Listener) handleRemovedLog(ctx context.Context, log *types.Log) error {
+	// block was already rolled back by other removed log
+	if log.BlockNumber == 0 || log.BlockNumber > l.blocks.Last() {
+		return nil
+	}
+
+	return l.rollback(ctx, log.BlockNumber-1)
+}
+
+// checkReorg - detects that block of the log replaced already known
+// one, or that its parent is not the block listener has seen. Then
+// state is rolled back to the common ancestor and canonical logs
+// before the block of the log are applied again.
+func (l *Listener) checkReorg(ctx context.Context, log *types.Log) error {
+	if known, ok := l.blocks.Hash(log.BlockNumber); ok && known == log.BlockHash {
+		return nil
+	}
+
+	// nothing to compare with
+	if l.blocks.Last() == 0 {
+		return nil
+	}
+
+	reorged, err := l.isReorged(ctx, log)
+	if err != nil {
+		return errors.Wrap(err, "failed to check block")
+	}
+
+	if !reorged {
+		return nil
+	}
+
+	ancestor, err := l.commonAncestor(ctx, log.BlockNumber-1)
+	if err != nil {
+		return errors.Wrap(err, "failed to find common ancestor")
+	}
+
+	if err := l.rollback(ctx, ancestor); err != nil {
+		return errors.Wrap(err, "failed to rollback")
+	}
+
+	if ancestor+1 > log.BlockNumber-1 {
+		return nil
+	}
+
+	query, err := l.filters()
+	if err != nil {
+		return errors.Wrap(err, "failed to get filters")
+	}
+
+	return errors.Wrap(
+		l.backfill(ctx, query, ancestor+1, log.BlockNumber-1),
+		"failed to apply canonical logs",
+	)
+}
+
+// checkCheckpoint - compares the latest block restored from snapshot
+// with canonical chain, and rolls back changes of blocks that were
+// reorganized while listener was down
+func (l *Listener) checkCheckpoint(ctx context.Context) error {
+	last := l.blocks.Last()
+	if last == 0 {
+		return nil
+	}
+
+	header, err := l.client.HeaderByNumber(ctx, new(big.Int).SetUint64(last))
+	if err != nil {
+		return errors.Wrap(err, "failed to get block header", logan.F{
+			"block": last,
+		})
+	}
+
+	if known, _ := l.blocks.Hash(last); known == header.Hash() {
+		return nil
+	}
+
+	ancestor, err := l.commonAncestor(ctx, last)
+	if err != nil {
+		return errors.Wrap(err, "failed to find common ancestor")
+	}
+
+	return errors.Wrap(l.rollback(ctx, ancestor), "failed to rollback")
+}
+
+// isReorged - returns true if block of the log is not a child of the
+// known chain. New block's parent is checked with its header, that is
+// also used to remember parent's hash.
+func (l *Listener) isReorged(ctx context.Context, log *types.Log) (bool, error) {
+	// known block was replaced, or logs are received
+	// for the block that listener has already passed
+	if log.BlockNumber <= l.blocks.Last() {
+		return true, nil
+	}
+
+	header, err := l.client.HeaderByHash(ctx, log.BlockHash)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get block header", logan.F{
+			"block": log.BlockNumber,
+		})
+	}
+
+	parent := log.BlockNumber - 1
+
+	if known, ok := l.blocks.Hash(parent); ok {
+		return known != header.ParentHash, nil
+	}
+
+	l.blocks.Add(parent, header.ParentHash)
+
+	return false, nil
+}
+
+// commonAncestor - returns the latest known block that is not later
+// than from and is still in the canonical chain. If reorg is deeper
+// than known history, block before the oldest known one is returned.
+func (l *Listener) commonAncestor(ctx context.Context, from uint64) (uint64, error) {
+	numbers := l.blocks.Numbers()
+
+	for _, number := range numbers {
+		if number > from {
+			continue
+		}
+
+		header, err := l.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get block header", logan.F{
+				"block": number,
+			})
+		}
+
+		if known, _ := l.blocks.Hash(number); known == header.Hash() {
+			return number, nil
+		}
+	}
+
+	if len(numbers) == 0 || numbers[len(numbers)-1] == 0 {
+		return 0, nil
+	}
+
+	oldest := numbers[len(numbers)-1]
+
+	l.logger.WithField("block", oldest).Warn("reorg is deeper than known blocks history")
+
+	return oldest - 1, nil
+}
+
+// rollback - forgets blocks after the block and sends event
+// to revert changes that were made by their logs
+func (l *Listener) rollback(ctx context.Context, block uint64) error {
+	l.logger.WithFields(logan.F{
+		"from": l.blocks.Last(),
+		"to":   block,
+	}).Warn("chain reorganization detected")
+
+	l.blocks.Rollback(block)
+	// logs of canonical chain after the block should be processed
+	l.lastLog = data.EndOfBlock(block)
+
+	if err := l.currentBlock.UpdateBlock(ctx, block); err != nil {
+		return errors.Wrap(err, "failed to update current block")
+	}
+
+	err := l.eventQueue.Send(ctx, channels.Event{
+		Type: channels.RollbackEvent,
+		Rollback: &channels.Rollback{
+			Block: block,
+		},
+	})
+
+	return errors.Wrap(err, "failed to send rollback event")
+}
@@ -0,0 +1,151 @@
+package listener
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/contracts"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/internal/providers"
+	"github.com/Velnbur/uniswapv2-indexer/pkg/logarchive"
+)
+
+// NewReplayer - creates listener that applies logs from archive without
+// Ethereum node and doesn't touch state of running service. Pairs are
+// registered only from snapshot and archived pair registrations.
+func NewReplayer(cfg config.Config, queue channels.EventQueue) (*Listener, error) {
+	pairABI, factoryABI, err := parseABIs()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ABIs")
+	}
+
+	eventUnpacker, err := NewEventUnpacker()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create event unpacker")
+	}
+
+	logger := cfg.Log().WithField("service", "replay")
+
+	uniswapV2, err := contracts.NewUniswapV2(
+		cfg.ContracterCfg().Factories, nil, nil, logger, nil, nil, nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create uniswapv2 contracts")
+	}
+
+	listener := &Listener{
+		logger:        logger,
+		pairABI:       pairABI,
+		factoryABI:    factoryABI,
+		uniswapV2:     uniswapV2,
+		discoveryCfg:  cfg.DiscoveryCfg(),
+		createdPairs:  make(map[common.Address]*channels.PairCreation),
+		currentBlock:  providers.NewBlockMemoryProvider(),
+		ethereumCfg:   cfg.EthereumCfg(),
+		blocks:        newBlocksHistory(cfg.EthereumCfg().ReorgDepth),
+		eventQueue:    queue,
+		eventUnpacker: eventUnpacker,
+	}
+	listener.initHandlers(pairABI, factoryABI)
+	// pairs are registered from archived registrations, which keep
+	// decisions that listener made with node and tokens config
+	listener.eventHandlers[factoryABI.Events[PairCreatedEvent.String()].ID] = skipLog
+
+	return listener, nil
+}
+
+// Replay - applies logs from archive in the order they were received.
+// If snapshot is set, its pairs are registered, and logs that were
+// applied before it are skipped.
+func (l *Listener) Replay(ctx context.Context, dir string, snapshot *data.GraphSnapshot) error {
+	var start data.LogPosition
+	if snapshot != nil {
+		if err := l.registerSnapshotPairs(snapshot); err != nil {
+			return errors.Wrap(err, "failed to register snapshot pairs")
+		}
+
+		start = snapshot.Position
+	}
+
+	var replayed int
+
+	err := logarchive.Read(dir, func(log *types.Log) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !logPosition(log).After(start) {
+			return nil
+		}
+
+		if err := l.replayLog(ctx, log); err != nil {
+			l.logger.WithError(err).WithFields(logan.F{
+				"block":     log.BlockNumber,
+				"log_index": log.Index,
+			}).Error("failed to replay log")
+		}
+
+		replayed++
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read archive", logan.F{
+			"dir": dir,
+		})
+	}
+
+	l.logger.WithFields(logan.F{
+		"logs":  replayed,
+		"pairs": l.uniswapV2.Pairs.Len(),
+	}).Info("archive replayed")
+
+	return nil
+}
+
+// replayLog - applies log from archive. Reorgs, that listener detected
+// with node, are detected by hashes of known blocks, as canonical logs
+// were archived after rollback.
+func (l *Listener) replayLog(ctx context.Context, log *types.Log) error {
+	// registration is not a log of chain, so it
+	// changes neither known blocks nor last log
+	if isPairRegistration(log) {
+		return errors.Wrap(l.replayPairRegistration(ctx, log), "failed to replay pair registration")
+	}
+
+	if log.Removed {
+		return errors.Wrap(l.handleRemovedLog(ctx, log), "failed to handle removed log")
+	}
+
+	if l.isReplayedReorg(log) {
+		if err := l.rollback(ctx, log.BlockNumber-1); err != nil {
+			return errors.Wrap(err, "failed to rollback")
+		}
+	}
+
+	return l.applyLog(ctx, log)
+}
+
+// isReplayedReorg - returns true if block of the log replaced known one,
+// or if log is earlier than already applied blocks
+func (l *Listener) isReplayedReorg(log *types.Log) bool {
+	if log.BlockNumber == 0 {
+		return false
+	}
+
+	if known, ok := l.blocks.Hash(log.BlockNumber); ok {
+		return known != log.BlockHash
+	}
+
+	return log.BlockNumber < l.blocks.Last()
+}
+
+func skipLog(context.Context, *types.Log) error {
+	return nil
+}
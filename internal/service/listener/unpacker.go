@@ -1,33 +1,35 @@
 package listener
 
 import (
-	abiPkg "github.com/ethereum/go-ethereum/accounts/abi"
-	"gitlab.com/distributed_lab/logan/v3"
+	"github.com/ethereum/go-ethereum/common"
 	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	uniswapv2factory "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-factory"
+	uniswapv2pair "github.com/Velnbur/uniswapv2-indexer/generated/uniswapv2-pair"
 )
 
-// EventUnpacker - from ABI unpackes configured events
-// into structs
+// EventUnpacker - decodes logs of configured events with generated
+// filterers, including indexed arguments, that are stored in topics
 type EventUnpacker struct {
-	pair    *abiPkg.ABI
-	factory *abiPkg.ABI
-}
-
-func NewEventUnpacker(pair, factory *abiPkg.ABI) *EventUnpacker {
-	return &EventUnpacker{pair, factory}
+	pair    *uniswapv2pair.UniswapV2PairFilterer
+	factory *uniswapv2factory.UniswapV2FactoryFilterer
 }
 
-func (e *EventUnpacker) Unpack(dest interface{}, event EventKey, data []byte) error {
-	var err error
+func NewEventUnpacker() (*EventUnpacker, error) {
+	// filterers are only used to parse logs, so they
+	// are bound neither to address nor to node
+	pair, err := uniswapv2pair.NewUniswapV2PairFilterer(common.Address{}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create pair filterer")
+	}
 
-	switch event {
-	case SwapEvent, SyncEvent, MintEvent, BurnEvent:
-		err = e.pair.UnpackIntoInterface(dest, string(event), data)
-	case PairCreatedEvent:
-		err = e.factory.UnpackIntoInterface(dest, string(event), data)
+	factory, err := uniswapv2factory.NewUniswapV2FactoryFilterer(common.Address{}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create factory filterer")
 	}
 
-	return errors.Wrap(err, "failed to unpack event", logan.F{
-		"event": event,
-	})
+	return &EventUnpacker{
+		pair:    pair,
+		factory: factory,
+	}, nil
 }
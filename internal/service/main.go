@@ -16,6 +16,9 @@ var services = map[string]Runner{
 	"api":      api.Run,
 	"listener": listener.Run,
 	"indexer":  indexer.Run,
+	// pending-indexer - applies events before they are confirmed,
+	// exits immediately if pending view is disabled
+	"pending-indexer": indexer.RunPending,
 }
 
 func Run(ctx context.Context, cfg config.Config) {
@@ -0,0 +1,117 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/Velnbur/uniswapv2-indexer/internal/channels"
+	"github.com/Velnbur/uniswapv2-indexer/internal/config"
+	"github.com/Velnbur/uniswapv2-indexer/internal/data"
+	"github.com/Velnbur/uniswapv2-indexer/internal/service/indexer"
+	"github.com/Velnbur/uniswapv2-indexer/internal/service/listener"
+)
+
+type Options struct {
+	// Archive - directory of logs archive
+	Archive string
+	// Snapshot - optional file with graph snapshot, from
+	// which replay is started instead of empty graph
+	Snapshot string
+	// Output - optional file, where snapshot of
+	// resulting graph is written
+	Output string
+}
+
+// Run - feeds logs from archive through listener handlers into indexer
+// without Ethereum node, so the same archive always results into the
+// same graph
+func Run(ctx context.Context, cfg config.Config, opts Options) error {
+	var snapshot *data.GraphSnapshot
+	if opts.Snapshot != "" {
+		snapshot = new(data.GraphSnapshot)
+		if err := readJSON(opts.Snapshot, snapshot); err != nil {
+			return errors.Wrap(err, "failed to read snapshot")
+		}
+	}
+
+	queue := make(chanQueue, channels.DefaultEventsChanLen)
+
+	replayer, err := listener.NewReplayer(cfg, queue)
+	if err != nil {
+		return errors.Wrap(err, "failed to create replayer")
+	}
+
+	result := make(chan *data.GraphSnapshot, 1)
+	go func() {
+		result <- indexer.NewReplayer(cfg).Replay(ctx, snapshot, queue)
+	}()
+
+	err = replayer.Replay(ctx, opts.Archive, snapshot)
+	close(queue)
+	if err != nil {
+		return errors.Wrap(err, "failed to replay archive")
+	}
+
+	graph := <-result
+
+	cfg.Log().WithFields(logan.F{
+		"block":     graph.Position.BlockNumber,
+		"log_index": graph.Position.LogIndex,
+		"pairs":     len(graph.Edges),
+	}).Info("graph replayed")
+
+	if opts.Output == "" {
+		return nil
+	}
+
+	return errors.Wrap(writeJSON(opts.Output, graph), "failed to write snapshot")
+}
+
+func readJSON(path string, dest interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file", logan.F{
+			"path": path,
+		})
+	}
+	defer file.Close()
+
+	return errors.Wrap(json.NewDecoder(file).Decode(dest), "failed to decode file", logan.F{
+		"path": path,
+	})
+}
+
+func writeJSON(path string, src interface{}) error {
+	raw, err := json.MarshalIndent(src, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+
+	return errors.Wrap(os.WriteFile(path, raw, 0o644), "failed to write file", logan.F{
+		"path": path,
+	})
+}
+
+// chanQueue - passes events from replayer directly to indexer,
+// and is closed when archive is replayed
+type chanQueue chan channels.Event
+
+func (q chanQueue) Send(ctx context.Context, events ...channels.Event) error {
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case q <- event:
+		}
+	}
+
+	return nil
+}
+
+func (q chanQueue) Receive(_ context.Context) (<-chan channels.Event, error) {
+	return q, nil
+}
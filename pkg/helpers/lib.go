@@ -6,7 +6,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-var ZeroAddress = common.Address{}
+var (
+	ZeroAddress = common.Address{}
+	ZeroHash    = common.Hash{}
+)
 
 func IsCanceled(ctx context.Context) bool {
 	select {
@@ -20,3 +23,7 @@ func IsCanceled(ctx context.Context) bool {
 func IsAddressZero(address common.Address) bool {
 	return address == ZeroAddress
 }
+
+func IsHashZero(hash common.Hash) bool {
+	return hash == ZeroHash
+}
@@ -0,0 +1,181 @@
+package logarchive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// DefaultSegmentSize - size of segment file after
+// which the next one is started
+const DefaultSegmentSize = 64 << 20
+
+const segmentPattern = "segment-*.jsonl"
+
+func segmentName(index int) string {
+	return fmt.Sprintf("segment-%08d.jsonl", index)
+}
+
+// Writer - appends logs to segment files in directory, one JSON
+// encoded log per line, including block and transaction hashes,
+// log index and removed flag
+type Writer struct {
+	dir         string
+	segmentSize int64
+
+	file  *os.File
+	index int
+	size  int64
+}
+
+// NewWriter - opens archive in directory, creating it if needed.
+// New segment is started, so the last line of previous one, that
+// could be truncated by crash, is never continued.
+func NewWriter(dir string, segmentSize int64) (*Writer, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create archive directory", logan.F{
+			"dir": dir,
+		})
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list segments")
+	}
+
+	writer := &Writer{
+		dir:         dir,
+		segmentSize: segmentSize,
+		index:       len(segments),
+	}
+
+	if err := writer.open(); err != nil {
+		return nil, errors.Wrap(err, "failed to open segment")
+	}
+
+	return writer, nil
+}
+
+func (w *Writer) open() error {
+	path := filepath.Join(w.dir, segmentName(w.index+1))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open segment file", logan.F{
+			"path": path,
+		})
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "failed to stat segment file", logan.F{
+			"path": path,
+		})
+	}
+
+	w.file = file
+	w.index++
+	w.size = info.Size()
+
+	return nil
+}
+
+// Write - appends log to the archive, starting new
+// segment if the current one is full
+func (w *Writer) Write(log *types.Log) error {
+	if w.size >= w.segmentSize {
+		if err := w.file.Close(); err != nil {
+			return errors.Wrap(err, "failed to close segment")
+		}
+
+		if err := w.open(); err != nil {
+			return errors.Wrap(err, "failed to open segment")
+		}
+	}
+
+	raw, err := json.Marshal(log)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal log")
+	}
+
+	n, err := w.file.Write(append(raw, '\n'))
+	w.size += int64(n)
+
+	return errors.Wrap(err, "failed to write log")
+}
+
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// Read - calls f for every log of archive in order they were
+// written. Truncated last line of segment, that could be left
+// by crash, is ignored.
+func Read(dir string, f func(log *types.Log) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to list segments")
+	}
+
+	for _, segment := range segments {
+		if err := readSegment(segment, f); err != nil {
+			return errors.Wrap(err, "failed to read segment", logan.F{
+				"segment": segment,
+			})
+		}
+	}
+
+	return nil
+}
+
+func readSegment(path string, f func(log *types.Log) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open segment file")
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	for {
+		var log types.Log
+
+		err := decoder.Decode(&log)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to decode log")
+		}
+
+		if err := f(&log); err != nil {
+			return err
+		}
+	}
+}
+
+// listSegments - returns paths of segments in order they were written
+func listSegments(dir string) ([]string, error) {
+	segments, err := filepath.Glob(filepath.Join(dir, segmentPattern))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find segments")
+	}
+
+	// names have fixed width, so they are ordered lexicographically
+	sort.Strings(segments)
+
+	return segments, nil
+}
@@ -0,0 +1,83 @@
+package logarchive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLog(block uint64, index uint, removed bool) *types.Log {
+	return &types.Log{
+		Address:     common.HexToAddress("0x1"),
+		Topics:      []common.Hash{common.HexToHash("0x2")},
+		Data:        []byte{1, 2, 3},
+		BlockNumber: block,
+		TxHash:      common.HexToHash("0x3"),
+		TxIndex:     1,
+		BlockHash:   common.HexToHash("0x4"),
+		Index:       index,
+		Removed:     removed,
+	}
+}
+
+func readAll(t *testing.T, dir string) []*types.Log {
+	var logs []*types.Log
+
+	err := Read(dir, func(log *types.Log) error {
+		logs = append(logs, log)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return logs
+}
+
+func Test_Archive(t *testing.T) {
+	t.Run("logs are read in order they were written", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// small segments, so every log is in its own one
+		writer, err := NewWriter(dir, 1)
+		require.NoError(t, err)
+
+		written := []*types.Log{testLog(1, 0, false), testLog(2, 1, false), testLog(2, 1, true)}
+		for _, log := range written {
+			require.NoError(t, writer.Write(log))
+		}
+		require.NoError(t, writer.Close())
+
+		segments, err := listSegments(dir)
+		require.NoError(t, err)
+		assert.Len(t, segments, 3)
+
+		assert.Equal(t, written, readAll(t, dir))
+	})
+
+	t.Run("truncated log is ignored after restart", func(t *testing.T) {
+		dir := t.TempDir()
+
+		writer, err := NewWriter(dir, DefaultSegmentSize)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(testLog(1, 0, false)))
+		require.NoError(t, writer.Close())
+
+		segment := filepath.Join(dir, segmentName(1))
+		file, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"address":"0x`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		writer, err = NewWriter(dir, DefaultSegmentSize)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(testLog(2, 0, false)))
+		require.NoError(t, writer.Close())
+
+		assert.Equal(t, []*types.Log{testLog(1, 0, false), testLog(2, 0, false)}, readAll(t, dir))
+	})
+}
@@ -36,6 +36,230 @@ func SwapAmountOut(
 	return result
 }
 
+// Uniswap V2 takes 0.3% fee from every swap, that is expressed as
+// multiplying input amount by FeeNumerator/FeeDenominator
+const (
+	FeeNumerator   = 997
+	FeeDenominator = 1000
+)
+
+// Fee - part of input amount that is left after swap fee, as
+// Numerator/Denominator. V2 forks use the same formula with
+// different values.
+type Fee struct {
+	Numerator   uint64 `json:"numerator"`
+	Denominator uint64 `json:"denominator"`
+}
+
+// DefaultFee - fee of Uniswap V2 pairs
+var DefaultFee = Fee{Numerator: FeeNumerator, Denominator: FeeDenominator}
+
+func (f Fee) IsZero() bool {
+	return f.Denominator == 0
+}
+
+// Float64 - returns Numerator/Denominator
+func (f Fee) Float64() float64 {
+	return float64(f.Numerator) / float64(f.Denominator)
+}
+
+func (f Fee) float() *big.Float {
+	return new(big.Float).SetPrec(floatPrec).Quo(
+		new(big.Float).SetPrec(floatPrec).SetUint64(f.Numerator),
+		new(big.Float).SetPrec(floatPrec).SetUint64(f.Denominator),
+	)
+}
+
+// GetAmountOut - calculates amount of output token that you will get
+// for amountIn of input token, the same way as UniswapV2Library.getAmountOut
+// does. Returns zero if there is no liquidity or amountIn is not positive.
+func GetAmountOut(amountIn, reserveIn, reserveOut *big.Int, fee Fee) *big.Int {
+	if amountIn.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	amountInWithFee := new(big.Int).Mul(amountIn, new(big.Int).SetUint64(fee.Numerator))
+
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Mul(reserveIn, new(big.Int).SetUint64(fee.Denominator))
+	denominator.Add(denominator, amountInWithFee)
+
+	return numerator.Quo(numerator, denominator)
+}
+
+// GetAmountsOut - performs chained GetAmountOut calculations for every hop,
+// where reservesIn[i] and reservesOut[i] are reserves of input and output
+// tokens of i-th hop and fees[i] is its fee. First element of result is
+// amountIn, the last one is the final output amount.
+func GetAmountsOut(reservesIn, reservesOut []*big.Int, fees []Fee, amountIn *big.Int) []*big.Int {
+	amounts := make([]*big.Int, len(reservesIn)+1)
+	amounts[0] = new(big.Int).Set(amountIn)
+
+	for i := range reservesIn {
+		amounts[i+1] = GetAmountOut(amounts[i], reservesIn[i], reservesOut[i], fees[i])
+	}
+
+	return amounts
+}
+
+// GetAmountIn - calculates minimal amount of input token that is required
+// to get amountOut of output token, the same way as UniswapV2Library.getAmountIn
+// does. Returns nil if pair has not enough liquidity for such output.
+func GetAmountIn(amountOut, reserveIn, reserveOut *big.Int, fee Fee) *big.Int {
+	if amountOut.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Cmp(amountOut) <= 0 {
+		return nil
+	}
+
+	numerator := new(big.Int).Mul(reserveIn, amountOut)
+	numerator.Mul(numerator, new(big.Int).SetUint64(fee.Denominator))
+
+	denominator := new(big.Int).Sub(reserveOut, amountOut)
+	denominator.Mul(denominator, new(big.Int).SetUint64(fee.Numerator))
+
+	result := numerator.Quo(numerator, denominator)
+
+	return result.Add(result, big.NewInt(1))
+}
+
+// GetAmountsIn - performs chained GetAmountIn calculations for every hop
+// starting from the last one. First element of result is required amount in,
+// the last one is amountOut. Returns nil if some of the hops can't provide
+// required output.
+func GetAmountsIn(reservesIn, reservesOut []*big.Int, fees []Fee, amountOut *big.Int) []*big.Int {
+	amounts := make([]*big.Int, len(reservesIn)+1)
+	amounts[len(amounts)-1] = new(big.Int).Set(amountOut)
+
+	for i := len(reservesIn) - 1; i >= 0; i-- {
+		amounts[i] = GetAmountIn(amounts[i+1], reservesIn[i], reservesOut[i], fees[i])
+		if amounts[i] == nil {
+			return nil
+		}
+	}
+
+	return amounts
+}
+
+// floatPrec - precision of big.Float calculations, that is enough
+// to keep uint256 values without losses
+const floatPrec = 512
+
+// OptimalAmountIn - calculates amount in that maximizes profit of the
+// swap along cyclic path, where reservesIn[i] and reservesOut[i] are
+// reserves of i-th hop and fees[i] is its fee. All hops are merged into one
+// virtual pair with reserves Ea and Eb and fee of the first hop, then optimal
+// amount is (sqrt(Ea*Eb*fee) - Ea) / fee. Returns zero if there is no
+// profitable amount.
+func OptimalAmountIn(reservesIn, reservesOut []*big.Int, fees []Fee) *big.Int {
+	if len(reservesIn) == 0 {
+		return big.NewInt(0)
+	}
+
+	fee := fees[0].float()
+
+	ea := new(big.Float).SetPrec(floatPrec).SetInt(reservesIn[0])
+	eb := new(big.Float).SetPrec(floatPrec).SetInt(reservesOut[0])
+
+	for i := 1; i < len(reservesIn); i++ {
+		reserveIn := new(big.Float).SetPrec(floatPrec).SetInt(reservesIn[i])
+		reserveOut := new(big.Float).SetPrec(floatPrec).SetInt(reservesOut[i])
+		hopFee := fees[i].float()
+
+		// denominator = reserveIn + hopFee * eb
+		denominator := new(big.Float).SetPrec(floatPrec).Mul(hopFee, eb)
+		denominator.Add(denominator, reserveIn)
+
+		if denominator.Sign() == 0 {
+			return big.NewInt(0)
+		}
+
+		// ea = ea * reserveIn / denominator
+		ea.Mul(ea, reserveIn).Quo(ea, denominator)
+		// eb = hopFee * eb * reserveOut / denominator
+		eb.Mul(eb, hopFee).Mul(eb, reserveOut).Quo(eb, denominator)
+	}
+
+	// amountIn = (sqrt(ea * eb * fee) - ea) / fee
+	amountIn := new(big.Float).SetPrec(floatPrec).Mul(ea, eb)
+	amountIn.Mul(amountIn, fee).Sqrt(amountIn)
+	amountIn.Sub(amountIn, ea).Quo(amountIn, fee)
+
+	if amountIn.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	result, _ := amountIn.Int(nil)
+
+	return result
+}
+
+// BpsDenominator - number of basis points in 100%
+const BpsDenominator = 10_000
+
+// MidPrice - returns spot price of the pair as amount of output token
+// per one input token, without fee and price impact
+func MidPrice(reserveIn, reserveOut *big.Int) *big.Float {
+	return Price(reserveIn, reserveOut)
+}
+
+// ExecutionPrice - returns price that swap of amountIn for amountOut
+// actually got, as amount of output token per one input token
+func ExecutionPrice(amountIn, amountOut *big.Int) *big.Float {
+	return Price(amountIn, amountOut)
+}
+
+// Price - returns amountOut/amountIn, or zero if amountIn is zero
+func Price(amountIn, amountOut *big.Int) *big.Float {
+	if amountIn.Sign() == 0 {
+		return new(big.Float).SetPrec(floatPrec)
+	}
+
+	return new(big.Float).SetPrec(floatPrec).Quo(
+		new(big.Float).SetPrec(floatPrec).SetInt(amountOut),
+		new(big.Float).SetPrec(floatPrec).SetInt(amountIn),
+	)
+}
+
+// PriceImpactBps - returns how much execution price is worse than mid
+// price in basis points. Includes fee, the same way as Uniswap interface
+// shows it.
+func PriceImpactBps(midPrice, executionPrice *big.Float) float64 {
+	if midPrice.Sign() == 0 {
+		return 0
+	}
+
+	// impact = (1 - executionPrice / midPrice) * BpsDenominator
+	impact := new(big.Float).SetPrec(floatPrec).Quo(executionPrice, midPrice)
+	impact.Sub(big.NewFloat(1), impact)
+	impact.Mul(impact, big.NewFloat(BpsDenominator))
+
+	result, _ := impact.Float64()
+
+	return result
+}
+
+// MinAmountOut - returns minimal amount that should be received if
+// price could move against swap by slippageBps basis points
+func MinAmountOut(amountOut *big.Int, slippageBps uint64) *big.Int {
+	if slippageBps >= BpsDenominator {
+		return big.NewInt(0)
+	}
+
+	result := new(big.Int).Mul(amountOut, new(big.Int).SetUint64(BpsDenominator-slippageBps))
+
+	return result.Quo(result, big.NewInt(BpsDenominator))
+}
+
+// MaxAmountIn - returns maximal amount that should be sent to get exact
+// output if price could move against swap by slippageBps basis points
+func MaxAmountIn(amountIn *big.Int, slippageBps uint64) *big.Int {
+	result := new(big.Int).Mul(amountIn, new(big.Int).SetUint64(BpsDenominator+slippageBps))
+
+	// rounded up, so the amount is enough for exact output
+	result.Add(result, big.NewInt(BpsDenominator-1))
+
+	return result.Quo(result, big.NewInt(BpsDenominator))
+}
+
 // Product - return Product of all big integers in array
 func Product(nums ...*big.Int) *big.Int {
 	result := big.NewInt(1)
@@ -68,3 +68,159 @@ func Test_SwapAmountsOut(t *testing.T) {
 		t.Log(amountOut)
 	})
 }
+
+func Test_GetAmountOut(t *testing.T) {
+	t.Run("ETH -> USDT", func(t *testing.T) {
+		amountOut := GetAmountOut(
+			mustFromString(t, "1000000000000000000"),
+			mustFromString(t, "11904476979297547639664"),
+			mustFromString(t, "15161485837452"),
+			DefaultFee,
+		)
+		require.Equal(t, mustFromString(t, "1269668171"), amountOut)
+	})
+
+	t.Run("fee is applied", func(t *testing.T) {
+		amountOut := GetAmountOut(big.NewInt(1000), big.NewInt(1000), big.NewInt(1000), DefaultFee)
+		require.Equal(t, big.NewInt(499), amountOut)
+	})
+
+	t.Run("custom fee", func(t *testing.T) {
+		amountOut := GetAmountOut(big.NewInt(1000), big.NewInt(1000), big.NewInt(1000), Fee{1, 1})
+		require.Equal(t, big.NewInt(500), amountOut)
+	})
+
+	t.Run("no liquidity", func(t *testing.T) {
+		amountOut := GetAmountOut(big.NewInt(1), big.NewInt(0), big.NewInt(5), DefaultFee)
+		require.Zero(t, amountOut.Sign())
+	})
+}
+
+func Test_GetAmountsOut(t *testing.T) {
+	t.Run("USDT -> ETH -> DAI", func(t *testing.T) {
+		reservesIn := []*big.Int{
+			mustFromString(t, "15161485837452"),
+			mustFromString(t, "5165403989650444294732"),
+		}
+
+		reservesOut := []*big.Int{
+			mustFromString(t, "11904476979297547639664"),
+			mustFromString(t, "6587199298527047793486029"),
+		}
+
+		amounts := GetAmountsOut(reservesIn, reservesOut, []Fee{DefaultFee, DefaultFee}, big.NewInt(1000000))
+		require.Len(t, amounts, 3)
+		require.Equal(t, big.NewInt(1000000), amounts[0])
+		require.Equal(t, mustFromString(t, "782823193922500"), amounts[1])
+		require.Equal(t, mustFromString(t, "995302940522661771"), amounts[2])
+	})
+}
+
+func Test_GetAmountIn(t *testing.T) {
+	t.Run("ETH -> USDT", func(t *testing.T) {
+		amountIn := GetAmountIn(
+			mustFromString(t, "1269668171"),
+			mustFromString(t, "11904476979297547639664"),
+			mustFromString(t, "15161485837452"),
+			DefaultFee,
+		)
+		require.Equal(t, mustFromString(t, "999999999486803140"), amountIn)
+	})
+
+	t.Run("fee is applied", func(t *testing.T) {
+		amountIn := GetAmountIn(big.NewInt(499), big.NewInt(1000), big.NewInt(1000), DefaultFee)
+		require.Equal(t, big.NewInt(1000), amountIn)
+	})
+
+	t.Run("not enough liquidity", func(t *testing.T) {
+		amountIn := GetAmountIn(big.NewInt(1000), big.NewInt(1000), big.NewInt(1000), DefaultFee)
+		require.Nil(t, amountIn)
+	})
+}
+
+func Test_GetAmountsIn(t *testing.T) {
+	reservesIn := []*big.Int{
+		mustFromString(t, "15161485837452"),
+		mustFromString(t, "5165403989650444294732"),
+	}
+
+	reservesOut := []*big.Int{
+		mustFromString(t, "11904476979297547639664"),
+		mustFromString(t, "6587199298527047793486029"),
+	}
+
+	fees := []Fee{DefaultFee, DefaultFee}
+
+	t.Run("USDT -> ETH -> DAI", func(t *testing.T) {
+		amounts := GetAmountsIn(reservesIn, reservesOut, fees, mustFromString(t, "995302940522661771"))
+		require.Len(t, amounts, 3)
+		require.Equal(t, big.NewInt(1000000), amounts[0])
+		require.Equal(t, mustFromString(t, "782823193922500"), amounts[1])
+	})
+
+	t.Run("not enough liquidity", func(t *testing.T) {
+		amounts := GetAmountsIn(reservesIn, reservesOut, fees, mustFromString(t, "6587199298527047793486029"))
+		require.Nil(t, amounts)
+	})
+}
+
+func Test_OptimalAmountIn(t *testing.T) {
+	fees := []Fee{DefaultFee, DefaultFee}
+
+	t.Run("profitable cycle", func(t *testing.T) {
+		reservesIn := []*big.Int{big.NewInt(1_000_000), big.NewInt(1_000_000)}
+		reservesOut := []*big.Int{big.NewInt(2_000_000), big.NewInt(1_000_000)}
+
+		amountIn := OptimalAmountIn(reservesIn, reservesOut, fees)
+		require.Equal(t, big.NewInt(137_342), amountIn)
+
+		amounts := GetAmountsOut(reservesIn, reservesOut, fees, amountIn)
+		require.Equal(t, big.NewInt(137_342+56_306), amounts[len(amounts)-1])
+	})
+
+	t.Run("balanced pools", func(t *testing.T) {
+		reservesIn := []*big.Int{big.NewInt(1_000_000), big.NewInt(2_000_000)}
+		reservesOut := []*big.Int{big.NewInt(2_000_000), big.NewInt(1_000_000)}
+
+		require.Zero(t, OptimalAmountIn(reservesIn, reservesOut, fees).Sign())
+	})
+
+	t.Run("different fees", func(t *testing.T) {
+		reservesIn := []*big.Int{big.NewInt(1_000_000), big.NewInt(1_000_000)}
+		reservesOut := []*big.Int{big.NewInt(2_000_000), big.NewInt(1_000_000)}
+		fees := []Fee{DefaultFee, {Numerator: 9975, Denominator: 10000}}
+
+		profit := func(amountIn *big.Int) *big.Int {
+			amounts := GetAmountsOut(reservesIn, reservesOut, fees, amountIn)
+			return new(big.Int).Sub(amounts[len(amounts)-1], amountIn)
+		}
+
+		amountIn := OptimalAmountIn(reservesIn, reservesOut, fees)
+		require.Positive(t, profit(amountIn).Sign())
+
+		for _, delta := range []int64{-100, 100} {
+			other := new(big.Int).Add(amountIn, big.NewInt(delta))
+			require.LessOrEqual(t, profit(other).Cmp(profit(amountIn)), 0)
+		}
+	})
+}
+
+func Test_PriceImpactBps(t *testing.T) {
+	midPrice := MidPrice(big.NewInt(1000), big.NewInt(1000))
+	executionPrice := ExecutionPrice(big.NewInt(1000), big.NewInt(499))
+
+	require.InDelta(t, 5010, PriceImpactBps(midPrice, executionPrice), 1e-9)
+	require.Zero(t, PriceImpactBps(midPrice, midPrice))
+}
+
+func Test_MinAmountOut(t *testing.T) {
+	require.Equal(t, big.NewInt(496), MinAmountOut(big.NewInt(499), 50))
+	require.Equal(t, big.NewInt(499), MinAmountOut(big.NewInt(499), 0))
+	require.Zero(t, MinAmountOut(big.NewInt(499), BpsDenominator).Sign())
+}
+
+func Test_MaxAmountIn(t *testing.T) {
+	require.Equal(t, big.NewInt(502), MaxAmountIn(big.NewInt(499), 50))
+	require.Equal(t, big.NewInt(499), MaxAmountIn(big.NewInt(499), 0))
+	require.Equal(t, big.NewInt(998), MaxAmountIn(big.NewInt(499), BpsDenominator))
+}
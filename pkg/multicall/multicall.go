@@ -0,0 +1,230 @@
+package multicall
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// multicall3Hex - EIP-55 checksummed address of Multicall3 contract
+const multicall3Hex = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+// Multicall3Address - address of Multicall3 contract, that is the
+// same in Ethereum mainnet and most of other networks
+var Multicall3Address = common.HexToAddress(multicall3Hex)
+
+const multicall3ABI = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// ErrCallFailed - set to result of the call that reverted in batch
+var ErrCallFailed = errors.New("call failed")
+
+// Backend - node client that could send batches of JSON-RPC requests
+type Backend interface {
+	bind.ContractCaller
+	BlockNumber(ctx context.Context) (uint64, error)
+	BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error
+}
+
+// Call - call of contract method in batch
+type Call struct {
+	Target common.Address
+	Data   []byte
+}
+
+// Result - result of the call, Err is set if call failed,
+// and doesn't affect other calls of batch
+type Result struct {
+	Data []byte
+	Err  error
+}
+
+// Multicall - aggregates contract calls into batches, that are made
+// with Multicall3 contract, or with JSON-RPC batches of eth_call if
+// the contract is not deployed or fails
+type Multicall struct {
+	backend Backend
+	logger  *logan.Entry
+	abi     abi.ABI
+	address common.Address
+	// batchSize - maximum number of calls in one request
+	batchSize int
+}
+
+func New(backend Backend, address common.Address, batchSize int, logger *logan.Entry) (*Multicall, error) {
+	parsed, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse multicall ABI")
+	}
+
+	if batchSize <= 0 {
+		return nil, errors.New("batch size should be positive")
+	}
+
+	return &Multicall{
+		backend:   backend,
+		logger:    logger,
+		abi:       parsed,
+		address:   address,
+		batchSize: batchSize,
+	}, nil
+}
+
+// Call - makes calls at the block, or at the current head if block
+// is zero, and returns the block they were made at. All batches are
+// pinned to the same block, so results are consistent.
+func (m *Multicall) Call(ctx context.Context, block uint64, calls []Call) (uint64, []Result, error) {
+	if block == 0 {
+		head, err := m.backend.BlockNumber(ctx)
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "failed to get head block number")
+		}
+
+		block = head
+	}
+
+	results := make([]Result, 0, len(calls))
+
+	for from := 0; from < len(calls); from += m.batchSize {
+		to := from + m.batchSize
+		if to > len(calls) {
+			to = len(calls)
+		}
+
+		batch, err := m.aggregate(ctx, block, calls[from:to])
+		if err != nil {
+			m.logger.WithError(err).WithField("calls", to-from).
+				Debug("multicall failed, falling back to JSON-RPC batch")
+
+			batch, err = m.batch(ctx, block, calls[from:to])
+		}
+		if err != nil {
+			return 0, nil, errors.Wrap(err, "failed to make calls", logan.F{
+				"block": block,
+				"from":  from,
+				"to":    to,
+			})
+		}
+
+		results = append(results, batch...)
+	}
+
+	return block, results, nil
+}
+
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result3 struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// aggregate - makes calls with one aggregate3 call, that
+// allows every call to fail separately
+func (m *Multicall) aggregate(ctx context.Context, block uint64, calls []Call) ([]Result, error) {
+	packedCalls := make([]call3, len(calls))
+	for i, call := range calls {
+		packedCalls[i] = call3{
+			Target:       call.Target,
+			AllowFailure: true,
+			CallData:     call.Data,
+		}
+	}
+
+	input, err := m.abi.Pack("aggregate3", packedCalls)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pack calls")
+	}
+
+	output, err := m.backend.CallContract(ctx, ethereum.CallMsg{
+		To:   &m.address,
+		Data: input,
+	}, new(big.Int).SetUint64(block))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to call multicall")
+	}
+
+	// node returns empty result if there is no contract
+	if len(output) == 0 {
+		return nil, errors.New("multicall is not deployed")
+	}
+
+	unpacked, err := m.abi.Unpack("aggregate3", output)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unpack results")
+	}
+
+	var packedResults []result3
+	if err := m.abi.Methods["aggregate3"].Outputs.Copy(&packedResults, unpacked); err != nil {
+		return nil, errors.Wrap(err, "failed to copy results")
+	}
+
+	if len(packedResults) != len(calls) {
+		return nil, errors.From(errors.New("unexpected number of results"), logan.F{
+			"calls":   len(calls),
+			"results": len(packedResults),
+		})
+	}
+
+	results := make([]Result, len(calls))
+	for i, result := range packedResults {
+		if !result.Success {
+			results[i].Err = ErrCallFailed
+			continue
+		}
+
+		results[i].Data = result.ReturnData
+	}
+
+	return results, nil
+}
+
+// batch - makes calls with one JSON-RPC batch of eth_call requests
+func (m *Multicall) batch(ctx context.Context, block uint64, calls []Call) ([]Result, error) {
+	blockNumber := hexutil.EncodeUint64(block)
+
+	elems := make([]rpc.BatchElem, len(calls))
+	outputs := make([]hexutil.Bytes, len(calls))
+
+	for i, call := range calls {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]interface{}{
+					"to":   call.Target,
+					"data": hexutil.Bytes(call.Data),
+				},
+				blockNumber,
+			},
+			Result: &outputs[i],
+		}
+	}
+
+	if err := m.backend.BatchCallContext(ctx, elems); err != nil {
+		return nil, errors.Wrap(err, "failed to send batch")
+	}
+
+	results := make([]Result, len(calls))
+	for i, elem := range elems {
+		if elem.Error != nil {
+			results[i].Err = elem.Error
+			continue
+		}
+
+		results[i].Data = outputs[i]
+	}
+
+	return results, nil
+}
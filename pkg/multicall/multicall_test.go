@@ -0,0 +1,18 @@
+package multicall
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Multicall3Address(t *testing.T) {
+	t.Run("default address is valid", func(t *testing.T) {
+		require.True(t, common.IsHexAddress(multicall3Hex))
+	})
+
+	t.Run("default address matches its checksum", func(t *testing.T) {
+		require.Equal(t, multicall3Hex, Multicall3Address.Hex())
+	})
+}
@@ -0,0 +1,601 @@
+package multiclient
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var _ bind.ContractBackend = &Client{}
+
+// ErrNoEndpoints - returned when none of endpoints is connected
+var ErrNoEndpoints = errors.New("no available endpoints")
+
+// ErrNoSyncedEndpoints - returned when none of connected endpoints
+// has the latest seen block
+var ErrNoSyncedEndpoints = errors.New("no endpoints with seen block")
+
+// headsRefreshInterval - minimal interval between synchronous
+// requests of endpoints heads, when none of them is known to have
+// the block that was already seen
+const headsRefreshInterval = time.Second
+
+// Client - Ethereum client that sends requests to several endpoints.
+// Request is sent to the best endpoint, and to the next ones if it
+// fails. Endpoints that don't have the latest seen block are not used,
+// so state is never read from the node that is behind the one that
+// gave the log, and request is retried until one of them has it.
+type Client struct {
+	endpoints []*endpoint
+	health    HealthConfig
+	retry     RetryConfig
+	logger    *logan.Entry
+
+	// seenBlock - the latest block that was observed by user
+	seenBlock uint64
+
+	refreshMux sync.Mutex
+	refreshed  time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Config - endpoints of client and its behaviour on failures
+type Config struct {
+	Endpoints []EndpointConfig
+	Health    HealthConfig
+	Retry     RetryConfig
+}
+
+// Dial - connects to endpoints and starts their health checks.
+// Endpoints that couldn't be dialed are dialed again later, but
+// at least one of them should be available.
+func Dial(ctx context.Context, cfg Config, logger *logan.Entry) (*Client, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, errors.New("no endpoints are configured")
+	}
+
+	c := &Client{
+		endpoints: make([]*endpoint, len(cfg.Endpoints)),
+		health:    cfg.Health,
+		retry:     cfg.Retry,
+		logger:    logger,
+		done:      make(chan struct{}),
+	}
+
+	connected := 0
+
+	for i, cfg := range cfg.Endpoints {
+		c.endpoints[i] = newEndpoint(cfg)
+
+		client, err := c.endpoints[i].dial(ctx)
+		if err != nil {
+			logger.WithError(err).WithField("endpoint", cfg.URL).Warn("failed to dial endpoint")
+			continue
+		}
+
+		c.endpoints[i].setClient(client)
+		connected++
+	}
+
+	if connected == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	c.checkHealth(ctx)
+
+	go c.monitor()
+
+	return c, nil
+}
+
+// Close - stops health checks and closes connections
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		for _, e := range c.endpoints {
+			if client := e.state().client; client != nil {
+				client.Close()
+			}
+		}
+	})
+}
+
+// SeenBlock - notifies client that block was observed, e.g. log
+// from it was received, so state is read only from endpoints
+// that have it
+func (c *Client) SeenBlock(block uint64) {
+	for {
+		seen := atomic.LoadUint64(&c.seenBlock)
+		if block <= seen || atomic.CompareAndSwapUint64(&c.seenBlock, seen, block) {
+			return
+		}
+	}
+}
+
+// SupportsSubscriptions - returns true if at least one of endpoints
+// allows eth_subscribe
+func (c *Client) SupportsSubscriptions() bool {
+	for _, e := range c.endpoints {
+		if e.supportsSubscriptions() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// candidates - returns connected endpoints that have the seen block in
+// order they should be tried: not throttled and healthy ones first, then
+// by priority and latency. Heads are requested again if none of endpoints
+// is known to have the block, and error is returned if it doesn't help.
+func (c *Client) candidates(ctx context.Context, subscription bool) ([]state, error) {
+	states := c.states(subscription)
+	if len(states) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	seen := atomic.LoadUint64(&c.seenBlock)
+	if !hasBlock(states, seen) && c.refreshHeads(ctx) {
+		states = c.states(subscription)
+	}
+
+	synced := make([]state, 0, len(states))
+	for _, s := range states {
+		if s.head >= seen {
+			synced = append(synced, s)
+		}
+	}
+
+	if len(synced) == 0 {
+		return nil, errors.From(ErrNoSyncedEndpoints, logan.F{
+			"seen_block": seen,
+		})
+	}
+
+	states = synced
+
+	var bestHead uint64
+	for _, s := range states {
+		if s.head > bestHead {
+			bestHead = s.head
+		}
+	}
+
+	sort.SliceStable(states, func(i, j int) bool {
+		a, b := states[i], states[j]
+
+		if a.throttled != b.throttled {
+			return b.throttled
+		}
+
+		if healthy := c.health.healthy(a, bestHead); healthy != c.health.healthy(b, bestHead) {
+			return healthy
+		}
+
+		if a.endpoint.Priority != b.endpoint.Priority {
+			return a.endpoint.Priority < b.endpoint.Priority
+		}
+
+		return a.latency < b.latency
+	})
+
+	return states, nil
+}
+
+func (c *Client) states(subscription bool) []state {
+	states := make([]state, 0, len(c.endpoints))
+
+	for _, e := range c.endpoints {
+		if subscription && !e.supportsSubscriptions() {
+			continue
+		}
+
+		if s := e.state(); s.client != nil {
+			states = append(states, s)
+		}
+	}
+
+	return states
+}
+
+func hasBlock(states []state, block uint64) bool {
+	for _, s := range states {
+		if s.head >= block {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refreshHeads - requests heads of all endpoints, if it was not done
+// recently. Returns false if heads were not requested.
+func (c *Client) refreshHeads(ctx context.Context) bool {
+	c.refreshMux.Lock()
+	defer c.refreshMux.Unlock()
+
+	if time.Since(c.refreshed) < headsRefreshInterval {
+		return false
+	}
+
+	for _, e := range c.endpoints {
+		c.checkHead(ctx, e)
+	}
+
+	c.refreshed = time.Now()
+
+	return true
+}
+
+func (c *Client) do(ctx context.Context, method string, f func(client *ethclient.Client) error) error {
+	return c.call(ctx, method, 1, func(s state) error {
+		return f(s.client)
+	})
+}
+
+// call - calls f with endpoints in order of preference, until it
+// succeeds or fails with error that is not caused by endpoint. If
+// all endpoints failed, request is retried after delay, that is not
+// less than the one throttling node asked for. Requests is the number
+// of method calls f makes, that are spent from endpoint limits.
+func (c *Client) call(ctx context.Context, method string, requests int, f func(s state) error) error {
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.try(ctx, method, requests, f)
+		if err == nil || !isEndpointError(ctx, method, err) || attempt >= c.retry.MaxRetries {
+			return err
+		}
+
+		delay := c.retry.delay(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		// heads are not requested again earlier
+		if errors.Cause(err) == ErrNoSyncedEndpoints && delay < headsRefreshInterval {
+			delay = headsRefreshInterval
+		}
+
+		c.logger.WithError(err).WithFields(logan.F{
+			"method":  method,
+			"attempt": attempt + 1,
+			"delay":   delay.String(),
+		}).Debug("request failed with all endpoints, retrying")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// try - calls f with every endpoint in order of preference, until it
+// succeeds or fails with error that is not caused by endpoint. Returns
+// the shortest delay throttling endpoints asked to wait.
+func (c *Client) try(
+	ctx context.Context, method string, requests int, f func(s state) error,
+) (time.Duration, error) {
+	candidates, err := c.candidates(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var retryAfter time.Duration
+
+	for _, s := range candidates {
+		if err := s.endpoint.limiter.Wait(ctx, method, requests); err != nil {
+			return 0, err
+		}
+
+		start := time.Now()
+
+		err = f(s)
+		if err == nil || !isEndpointError(ctx, method, err) {
+			s.endpoint.record(time.Since(start), false)
+			return 0, err
+		}
+
+		logger := c.logger.WithError(err).WithFields(logan.F{
+			"endpoint": s.endpoint.URL,
+			"method":   method,
+		})
+
+		// throttling says nothing about endpoint health
+		if hint, ok := s.endpoint.rateLimitHint(err); ok {
+			s.endpoint.throttle(hint)
+
+			if hint > 0 && (retryAfter == 0 || hint < retryAfter) {
+				retryAfter = hint
+			}
+
+			logger.WithField("retry_after", hint.String()).Debug("endpoint throttled request, trying next one")
+			continue
+		}
+
+		s.endpoint.record(0, true)
+
+		logger.Debug("endpoint request failed, trying next one")
+	}
+
+	return retryAfter, err
+}
+
+// isEndpointError - returns false if error of method would be the same
+// with any other endpoint, e.g. reverted call, too wide logs range or
+// canceled request
+func isEndpointError(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if method == "eth_getLogs" && IsLogsLimitError(err) {
+		return false
+	}
+
+	cause := errors.Cause(err)
+	if cause == ethereum.NotFound {
+		return false
+	}
+
+	// reverted calls have code 3, or only message if
+	// revert reason is not provided by node
+	if rpcErr, ok := cause.(rpc.Error); ok {
+		return rpcErr.ErrorCode() != 3 && !strings.Contains(rpcErr.Error(), "execution reverted")
+	}
+
+	return true
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var head uint64
+
+	err := c.do(ctx, "eth_blockNumber", func(client *ethclient.Client) (err error) {
+		head, err = client.BlockNumber(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return head, nil
+}
+
+func (c *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+
+	err := c.do(ctx, "eth_getBlockByHash", func(client *ethclient.Client) (err error) {
+		header, err = client.HeaderByHash(ctx, hash)
+		return err
+	})
+
+	return header, err
+}
+
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+
+	err := c.do(ctx, "eth_getBlockByNumber", func(client *ethclient.Client) (err error) {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+
+	return header, err
+}
+
+// HeaderByTag - returns header of the block with tag, e.g. "safe"
+// or "finalized", as ethclient supports only numbers and "latest"
+func (c *Client) HeaderByTag(ctx context.Context, tag string) (*types.Header, error) {
+	var header *types.Header
+
+	err := c.call(ctx, "eth_getBlockByNumber", 1, func(s state) error {
+		err := s.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", tag, false)
+		if err == nil && header == nil {
+			err = ethereum.NotFound
+		}
+		return err
+	})
+
+	return header, err
+}
+
+func (c *Client) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code []byte
+
+	err := c.do(ctx, "eth_getCode", func(client *ethclient.Client) (err error) {
+		code, err = client.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+
+	return code, err
+}
+
+func (c *Client) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+
+	err := c.do(ctx, "eth_call", func(client *ethclient.Client) (err error) {
+		result, err = client.CallContract(ctx, call, blockNumber)
+		return err
+	})
+
+	return result, err
+}
+
+func (c *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var code []byte
+
+	err := c.do(ctx, "eth_getCode", func(client *ethclient.Client) (err error) {
+		code, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+
+	return code, err
+}
+
+func (c *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var nonce uint64
+
+	err := c.do(ctx, "eth_getTransactionCount", func(client *ethclient.Client) (err error) {
+		nonce, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+
+	return nonce, err
+}
+
+func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var price *big.Int
+
+	err := c.do(ctx, "eth_gasPrice", func(client *ethclient.Client) (err error) {
+		price, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+
+	return price, err
+}
+
+func (c *Client) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var tip *big.Int
+
+	err := c.do(ctx, "eth_maxPriorityFeePerGas", func(client *ethclient.Client) (err error) {
+		tip, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+
+	return tip, err
+}
+
+func (c *Client) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	var gas uint64
+
+	err := c.do(ctx, "eth_estimateGas", func(client *ethclient.Client) (err error) {
+		gas, err = client.EstimateGas(ctx, call)
+		return err
+	})
+
+	return gas, err
+}
+
+// SendTransaction - sends transaction to the best endpoint only, without
+// retries, as node could broadcast it even if request failed
+func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	const method = "eth_sendRawTransaction"
+
+	candidates, err := c.candidates(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	s := candidates[0]
+	if err := s.endpoint.limiter.Wait(ctx, method, 1); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	err = s.client.SendTransaction(ctx, tx)
+	if err != nil && isEndpointError(ctx, method, err) {
+		if hint, ok := s.endpoint.rateLimitHint(err); ok {
+			s.endpoint.throttle(hint)
+			return err
+		}
+
+		s.endpoint.record(0, true)
+		return err
+	}
+
+	s.endpoint.record(time.Since(start), false)
+
+	return err
+}
+
+// BatchCallContext - sends requests of the same method in one JSON-RPC
+// batch. Errors of separate requests are set to their elements, and
+// returned error means that the whole batch failed with all endpoints.
+func (c *Client) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return c.call(ctx, batch[0].Method, len(batch), func(s state) error {
+		return s.rpc.BatchCallContext(ctx, batch)
+	})
+}
+
+func (c *Client) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+
+	err := c.do(ctx, "eth_getLogs", func(client *ethclient.Client) (err error) {
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+
+	return logs, err
+}
+
+// SubscribeFilterLogs - subscribes to logs with the best endpoint that
+// supports subscriptions. When subscription fails, endpoint health is
+// lowered, so the next subscription is made with other endpoint.
+func (c *Client) SubscribeFilterLogs(
+	ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	candidates, err := c.candidates(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range candidates {
+		if err := s.endpoint.limiter.Wait(ctx, "eth_subscribe", 1); err != nil {
+			return nil, err
+		}
+
+		var sub ethereum.Subscription
+
+		sub, err = s.client.SubscribeFilterLogs(ctx, query, ch)
+		if err != nil {
+			s.endpoint.record(0, true)
+
+			c.logger.WithError(err).WithField("endpoint", s.endpoint.URL).
+				Debug("failed to subscribe, trying next endpoint")
+			continue
+		}
+
+		return c.watch(s.endpoint, sub), nil
+	}
+
+	return nil, err
+}
+
+// watch - returns subscription that records its failure
+// to endpoint health
+func (c *Client) watch(e *endpoint, sub ethereum.Subscription) ethereum.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+
+		select {
+		case <-quit:
+			return nil
+		case err := <-sub.Err():
+			if err != nil {
+				e.record(0, true)
+			}
+
+			return err
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package multiclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// node - JSON-RPC server that answers eth_blockNumber with its head,
+// and other methods with respond, counting calls of every method
+type node struct {
+	*httptest.Server
+
+	head    uint64
+	respond func(w http.ResponseWriter, id json.RawMessage, method string)
+
+	mux   sync.Mutex
+	calls map[string]int
+}
+
+func newNode(t *testing.T, respond func(w http.ResponseWriter, id json.RawMessage, method string)) *node {
+	n := &node{
+		head:    100,
+		respond: respond,
+		calls:   make(map[string]int),
+	}
+
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		n.mux.Lock()
+		n.calls[req.Method]++
+		head := n.head
+		n.mux.Unlock()
+
+		if req.Method == "eth_blockNumber" {
+			writeResult(w, req.ID, hexutil.Uint64(head))
+			return
+		}
+
+		n.respond(w, req.ID, req.Method)
+	}))
+	t.Cleanup(n.Close)
+
+	return n
+}
+
+func (n *node) called(method string) int {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	return n.calls[method]
+}
+
+func (n *node) setHead(head uint64) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	n.head = head
+}
+
+func writeResult(w http.ResponseWriter, id json.RawMessage, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	})
+}
+
+func writeError(w http.ResponseWriter, id json.RawMessage, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]interface{}{"code": code, "message": message, "data": data},
+	})
+}
+
+// gasPrice - answers every method with the same gas price
+func gasPrice(w http.ResponseWriter, id json.RawMessage, _ string) {
+	writeResult(w, id, (*hexutil.Big)(big.NewInt(42)))
+}
+
+// unavailable - fails every method with HTTP error
+func unavailable(w http.ResponseWriter, _ json.RawMessage, _ string) {
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+func dial(t *testing.T, retry RetryConfig, nodes ...*node) *Client {
+	endpoints := make([]EndpointConfig, len(nodes))
+	for i, n := range nodes {
+		endpoints[i] = EndpointConfig{URL: n.URL, Priority: i}
+	}
+
+	c, err := Dial(context.Background(), Config{
+		Endpoints: endpoints,
+		Health: HealthConfig{
+			CheckInterval: time.Hour,
+			MaxHeadLag:    3,
+			MaxErrorRate:  0.5,
+		},
+		Retry: retry,
+	}, logan.New())
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+var noRetries = RetryConfig{MinDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func Test_Client(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("failover", func(t *testing.T) {
+		primary := newNode(t, unavailable)
+		secondary := newNode(t, gasPrice)
+
+		c := dial(t, noRetries, primary, secondary)
+
+		price, err := c.SuggestGasPrice(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(42), price.Int64())
+
+		require.Equal(t, 1, primary.called("eth_gasPrice"))
+		require.Equal(t, 1, secondary.called("eth_gasPrice"))
+	})
+
+	t.Run("failing endpoint becomes unhealthy", func(t *testing.T) {
+		primary := newNode(t, unavailable)
+		secondary := newNode(t, gasPrice)
+
+		c := dial(t, noRetries, primary, secondary)
+
+		// error rate exceeds 0.5 after 4 failures in a row
+		for i := 0; i < 5; i++ {
+			_, err := c.SuggestGasPrice(ctx)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 4, primary.called("eth_gasPrice"))
+		require.Equal(t, 5, secondary.called("eth_gasPrice"))
+
+		candidates, err := c.candidates(ctx, false)
+		require.NoError(t, err)
+		require.Equal(t, secondary.URL, candidates[0].endpoint.URL)
+	})
+
+	t.Run("retry after all endpoints failed", func(t *testing.T) {
+		n := newNode(t, unavailable)
+
+		c := dial(t, RetryConfig{
+			MaxRetries: 2,
+			MinDelay:   time.Millisecond,
+			MaxDelay:   time.Millisecond,
+		}, n)
+
+		_, err := c.SuggestGasPrice(ctx)
+		require.Error(t, err)
+		require.Equal(t, 3, n.called("eth_gasPrice"))
+	})
+
+	t.Run("throttled endpoint", func(t *testing.T) {
+		primary := newNode(t, func(w http.ResponseWriter, id json.RawMessage, _ string) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		secondary := newNode(t, gasPrice)
+
+		c := dial(t, noRetries, primary, secondary)
+
+		for i := 0; i < 2; i++ {
+			_, err := c.SuggestGasPrice(ctx)
+			require.NoError(t, err)
+		}
+
+		// throttled endpoint is the last candidate, but its health is not lowered
+		require.Equal(t, 1, primary.called("eth_gasPrice"))
+		require.Equal(t, 2, secondary.called("eth_gasPrice"))
+
+		state := primary.endpoint(c).state()
+		require.True(t, state.throttled)
+		require.Zero(t, state.errorRate)
+	})
+
+	t.Run("retry waits for hint", func(t *testing.T) {
+		var once sync.Once
+		n := newNode(t, func(w http.ResponseWriter, id json.RawMessage, method string) {
+			throttled := false
+			once.Do(func() { throttled = true })
+
+			if throttled {
+				writeError(w, id, -32005, "project ID request rate exceeded",
+					map[string]interface{}{"backoff_seconds": 0.2})
+				return
+			}
+
+			gasPrice(w, id, method)
+		})
+
+		c := dial(t, RetryConfig{
+			MaxRetries: 1,
+			MinDelay:   time.Millisecond,
+			MaxDelay:   time.Millisecond,
+		}, n)
+
+		start := time.Now()
+
+		_, err := c.SuggestGasPrice(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, n.called("eth_gasPrice"))
+		require.True(t, time.Since(start) >= 200*time.Millisecond)
+	})
+
+	t.Run("logs limit is not retried", func(t *testing.T) {
+		primary := newNode(t, func(w http.ResponseWriter, id json.RawMessage, _ string) {
+			writeError(w, id, -32005, "query returned more than 10000 results", nil)
+		})
+		secondary := newNode(t, gasPrice)
+
+		c := dial(t, RetryConfig{MaxRetries: 2, MinDelay: time.Millisecond, MaxDelay: time.Millisecond},
+			primary, secondary)
+
+		_, err := c.FilterLogs(ctx, ethereum.FilterQuery{})
+		require.True(t, IsLogsLimitError(err))
+
+		require.Equal(t, 1, primary.called("eth_getLogs"))
+		require.Zero(t, secondary.called("eth_getLogs"))
+		require.Zero(t, primary.endpoint(c).state().errorRate)
+	})
+
+	t.Run("lagging endpoints are not used", func(t *testing.T) {
+		primary := newNode(t, gasPrice)
+		secondary := newNode(t, gasPrice)
+		secondary.setHead(105)
+
+		c := dial(t, noRetries, primary, secondary)
+		c.SeenBlock(105)
+
+		for i := 0; i < 2; i++ {
+			_, err := c.SuggestGasPrice(ctx)
+			require.NoError(t, err)
+		}
+
+		require.Zero(t, primary.called("eth_gasPrice"))
+		require.Equal(t, 2, secondary.called("eth_gasPrice"))
+
+		// none of endpoints has the block
+		c.SeenBlock(110)
+
+		_, err := c.SuggestGasPrice(ctx)
+		require.Equal(t, ErrNoSyncedEndpoints, errors.Cause(err))
+		require.Equal(t, 2, secondary.called("eth_gasPrice"))
+	})
+
+	t.Run("request waits for synced endpoint", func(t *testing.T) {
+		n := newNode(t, gasPrice)
+
+		c := dial(t, RetryConfig{
+			MaxRetries: 1,
+			MinDelay:   time.Millisecond,
+			MaxDelay:   time.Millisecond,
+		}, n)
+		c.SeenBlock(105)
+
+		time.AfterFunc(100*time.Millisecond, func() { n.setHead(105) })
+
+		_, err := c.SuggestGasPrice(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, n.called("eth_gasPrice"))
+	})
+
+	t.Run("transaction is sent once", func(t *testing.T) {
+		primary := newNode(t, unavailable)
+		secondary := newNode(t, gasPrice)
+
+		c := dial(t, RetryConfig{MaxRetries: 2, MinDelay: time.Millisecond, MaxDelay: time.Millisecond},
+			primary, secondary)
+
+		tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+
+		require.Error(t, c.SendTransaction(ctx, tx))
+		require.Equal(t, 1, primary.called("eth_sendRawTransaction"))
+		require.Zero(t, secondary.called("eth_sendRawTransaction"))
+	})
+}
+
+// endpoint - returns endpoint of client that sends requests to node
+func (n *node) endpoint(c *Client) *endpoint {
+	for _, e := range c.endpoints {
+		if e.URL == n.URL {
+			return e
+		}
+	}
+
+	return nil
+}
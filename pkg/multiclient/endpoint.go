@@ -0,0 +1,194 @@
+package multiclient
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EndpointConfig - node that client could send requests to
+type EndpointConfig struct {
+	URL string
+	// Priority - endpoints with lower priority are preferred
+	// while they are healthy
+	Priority  int
+	RateLimit RateLimitConfig
+}
+
+// defaultThrottleDelay - how long endpoint is not used after it
+// throttled request, if it gave no hint
+const defaultThrottleDelay = time.Second
+
+// healthAlpha - weight of the latest request in moving averages
+// of endpoint error rate and latency
+const healthAlpha = 0.2
+
+type endpoint struct {
+	EndpointConfig
+
+	limiter *limiter
+
+	mux    sync.RWMutex
+	rpc    *rpc.Client
+	client *ethclient.Client
+	// head - the latest block endpoint is known to have
+	head uint64
+	// errorRate - moving average of failed requests part
+	errorRate float64
+	// latency - moving average of requests latency
+	latency time.Duration
+	// throttledUntil - time until which endpoint asked
+	// not to send requests
+	throttledUntil time.Time
+	// retryAfterUntil - time until which endpoint asked to wait
+	// with Retry-After header of the last throttled response
+	retryAfterUntil time.Time
+}
+
+func newEndpoint(cfg EndpointConfig) *endpoint {
+	return &endpoint{
+		EndpointConfig: cfg,
+		limiter:        newLimiter(cfg.RateLimit),
+	}
+}
+
+// state - copy of endpoint health, that is used to rank endpoints
+type state struct {
+	endpoint *endpoint
+
+	rpc       *rpc.Client
+	client    *ethclient.Client
+	head      uint64
+	errorRate float64
+	latency   time.Duration
+	throttled bool
+}
+
+func (e *endpoint) state() state {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	return state{
+		endpoint:  e,
+		rpc:       e.rpc,
+		client:    e.client,
+		head:      e.head,
+		errorRate: e.errorRate,
+		latency:   e.latency,
+		throttled: time.Now().Before(e.throttledUntil),
+	}
+}
+
+// supportsSubscriptions - returns true if endpoint is connected
+// with persistent connection, that allows eth_subscribe
+func (e *endpoint) supportsSubscriptions() bool {
+	return !strings.HasPrefix(e.URL, "http://") && !strings.HasPrefix(e.URL, "https://")
+}
+
+// dial - connects to endpoint, HTTP responses are inspected for
+// Retry-After header, as it is not passed with rpc errors
+func (e *endpoint) dial(ctx context.Context) (*rpc.Client, error) {
+	if e.supportsSubscriptions() {
+		return rpc.DialContext(ctx, e.URL)
+	}
+
+	return rpc.DialHTTPWithClient(e.URL, &http.Client{
+		Transport: &retryAfterTransport{
+			endpoint: e,
+			next:     http.DefaultTransport,
+		},
+	})
+}
+
+func (e *endpoint) setClient(client *rpc.Client) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	e.rpc = client
+	e.client = ethclient.NewClient(client)
+}
+
+// seenHead - remembers that endpoint has the block
+func (e *endpoint) seenHead(head uint64) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if head > e.head {
+		e.head = head
+	}
+}
+
+// record - updates moving averages with result of request
+func (e *endpoint) record(latency time.Duration, failed bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	var failure float64
+	if failed {
+		failure = 1
+	}
+
+	e.errorRate = e.errorRate*(1-healthAlpha) + failure*healthAlpha
+
+	// latency of failed requests says nothing about endpoint speed
+	if failed {
+		return
+	}
+
+	if e.latency == 0 {
+		e.latency = latency
+		return
+	}
+
+	e.latency = time.Duration(float64(e.latency)*(1-healthAlpha) + float64(latency)*healthAlpha)
+}
+
+// throttle - makes endpoint the last one to be used for delay
+func (e *endpoint) throttle(delay time.Duration) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if delay <= 0 {
+		delay = defaultThrottleDelay
+	}
+
+	e.throttledUntil = time.Now().Add(delay)
+}
+
+// rateLimitHint - returns true if endpoint throttled request, and
+// delay it asked to wait with error or Retry-After header
+func (e *endpoint) rateLimitHint(err error) (time.Duration, bool) {
+	hint, ok := rateLimitHint(err)
+	if ok && hint == 0 {
+		hint = e.retryAfter()
+	}
+
+	return hint, ok
+}
+
+// setRetryAfter - remembers delay endpoint asked to wait with
+// Retry-After header
+func (e *endpoint) setRetryAfter(delay time.Duration) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	e.retryAfterUntil = time.Now().Add(delay)
+}
+
+// retryAfter - returns time left of delay endpoint asked to wait
+// with Retry-After header, or zero if it has passed
+func (e *endpoint) retryAfter() time.Duration {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	if left := time.Until(e.retryAfterUntil); left > 0 {
+		return left
+	}
+
+	return 0
+}
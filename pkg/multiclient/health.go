@@ -0,0 +1,109 @@
+package multiclient
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// HealthConfig - thresholds after which endpoint is considered
+// unhealthy, and is used only if there are no healthy ones
+type HealthConfig struct {
+	// CheckInterval - how often heads of endpoints are requested,
+	// and disconnected endpoints are dialed again
+	CheckInterval time.Duration
+	// MaxHeadLag - number of blocks endpoint could be behind the
+	// best one
+	MaxHeadLag uint64
+	// MaxErrorRate - part of failed requests, from 0 to 1
+	MaxErrorRate float64
+	// MaxLatency - zero means that latency is not limited
+	MaxLatency time.Duration
+}
+
+var DefaultHealthConfig = HealthConfig{
+	CheckInterval: 10 * time.Second,
+	MaxHeadLag:    3,
+	MaxErrorRate:  0.5,
+}
+
+func (c HealthConfig) healthy(s state, bestHead uint64) bool {
+	if s.client == nil || s.errorRate > c.MaxErrorRate {
+		return false
+	}
+
+	if bestHead > s.head && bestHead-s.head > c.MaxHeadLag {
+		return false
+	}
+
+	return c.MaxLatency == 0 || s.latency <= c.MaxLatency
+}
+
+// monitor - checks endpoints health until client is closed
+func (c *Client) monitor() {
+	ticker := time.NewTicker(c.health.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.health.CheckInterval)
+			c.checkHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+// checkHealth - dials disconnected endpoints and requests heads
+// of connected ones, measuring their latency
+func (c *Client) checkHealth(ctx context.Context) {
+	for _, e := range c.endpoints {
+		if e.state().client == nil {
+			client, err := e.dial(ctx)
+			if err != nil {
+				c.logger.WithError(err).WithField("endpoint", e.URL).Debug("failed to dial endpoint")
+				continue
+			}
+
+			e.setClient(client)
+		}
+
+		c.checkHead(ctx, e)
+	}
+}
+
+func (c *Client) checkHead(ctx context.Context, e *endpoint) {
+	client := e.state().client
+	if client == nil {
+		return
+	}
+
+	if err := e.limiter.Wait(ctx, "eth_blockNumber", 1); err != nil {
+		return
+	}
+
+	start := time.Now()
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		e.record(0, true)
+
+		c.logger.WithError(err).WithField("endpoint", e.URL).Warn("endpoint health check failed")
+		return
+	}
+
+	e.record(time.Since(start), false)
+	e.seenHead(head)
+
+	state := e.state()
+
+	c.logger.WithFields(logan.F{
+		"endpoint":   e.URL,
+		"head":       head,
+		"latency":    state.latency.String(),
+		"error_rate": state.errorRate,
+	}).Debug("endpoint health checked")
+}
@@ -0,0 +1,49 @@
+package multiclient
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// logsLimitErrors - parts of errors that nodes return when eth_getLogs
+// range contains too many logs, or is too wide itself
+var logsLimitErrors = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response size should not greater than",
+	"block range",
+	"range is too large",
+	"too many results",
+	"limit exceeded",
+}
+
+// logsLimitCode - JSON-RPC error code that some nodes return when
+// eth_getLogs range contains too many logs, it is also used for
+// rate limiting, which is not fixed by smaller ranges
+const logsLimitCode = -32005
+
+// IsLogsLimitError - returns true if node refused eth_getLogs request
+// because of its range, so it would fail the same way with any other
+// endpoint and should be split into smaller ones
+func IsLogsLimitError(err error) bool {
+	if IsRateLimited(err) {
+		return false
+	}
+
+	cause := errors.Cause(err)
+	if rpcErr, ok := cause.(rpc.Error); ok && rpcErr.ErrorCode() == logsLimitCode {
+		return true
+	}
+
+	msg := strings.ToLower(cause.Error())
+
+	for _, substr := range logsLimitErrors {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+
+	return false
+}
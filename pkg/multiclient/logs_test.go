@@ -0,0 +1,36 @@
+package multiclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func Test_IsLogsLimitError(t *testing.T) {
+	t.Run("error code", func(t *testing.T) {
+		err := jsonError{code: -32005, message: "query returned more than 10000 results"}
+
+		require.True(t, IsLogsLimitError(errors.Wrap(err, "failed to filter logs")))
+	})
+
+	t.Run("error message", func(t *testing.T) {
+		err := jsonError{code: -32602, message: "Log response size exceeded"}
+
+		require.True(t, IsLogsLimitError(err))
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		err := jsonError{code: -32005, message: "project ID request rate exceeded"}
+
+		require.False(t, IsLogsLimitError(err))
+	})
+
+	t.Run("not retried with other endpoints", func(t *testing.T) {
+		err := jsonError{code: -32005, message: "query returned more than 10000 results"}
+
+		require.False(t, isEndpointError(context.Background(), "eth_getLogs", err))
+		require.True(t, isEndpointError(context.Background(), "eth_getLogs", errors.New("connection refused")))
+	})
+}
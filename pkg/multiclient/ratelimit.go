@@ -0,0 +1,248 @@
+package multiclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/cast"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig - limits of requests to endpoint, zero values
+// mean that there is no such limit
+type RateLimitConfig struct {
+	RequestsPerSecond float64
+	// ComputeUnitsPerSecond - budget of compute units, that every
+	// request spends according to MethodCosts
+	ComputeUnitsPerSecond int
+	// MethodCosts - compute units of JSON-RPC methods, methods
+	// that are not listed cost one unit
+	MethodCosts map[string]int
+}
+
+// limiter - waits until request to endpoint fits into its limits
+type limiter struct {
+	costs    map[string]int
+	requests *rate.Limiter
+	units    *rate.Limiter
+}
+
+func newLimiter(cfg RateLimitConfig) *limiter {
+	l := &limiter{
+		costs: cfg.MethodCosts,
+	}
+
+	if cfg.RequestsPerSecond > 0 {
+		burst := int(math.Ceil(cfg.RequestsPerSecond))
+		l.requests = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), burst)
+	}
+
+	if cfg.ComputeUnitsPerSecond > 0 {
+		// the most expensive request should fit into the bucket
+		burst := cfg.ComputeUnitsPerSecond
+		for _, cost := range cfg.MethodCosts {
+			if cost > burst {
+				burst = cost
+			}
+		}
+
+		l.units = rate.NewLimiter(rate.Limit(cfg.ComputeUnitsPerSecond), burst)
+	}
+
+	return l
+}
+
+// Wait - waits until n requests of method fit into limits, batch
+// of requests is counted as one request
+func (l *limiter) Wait(ctx context.Context, method string, n int) error {
+	if l.requests != nil {
+		if err := l.requests.Wait(ctx); err != nil {
+			return errors.Wrap(err, "failed to wait for requests limit")
+		}
+	}
+
+	if l.units != nil {
+		cost, ok := l.costs[method]
+		if !ok {
+			cost = 1
+		}
+
+		// batch could exceed budget of one second, then
+		// it waits for the budget by parts
+		for total := cost * n; total > 0; total -= l.units.Burst() {
+			units := total
+			if units > l.units.Burst() {
+				units = l.units.Burst()
+			}
+
+			if err := l.units.WaitN(ctx, units); err != nil {
+				return errors.Wrap(err, "failed to wait for compute units limit")
+			}
+		}
+	}
+
+	return nil
+}
+
+// RetryConfig - retries of requests that failed with all endpoints
+type RetryConfig struct {
+	MaxRetries int
+	// MinDelay, MaxDelay - bounds of exponential delay between
+	// retries, that is used if node gives no hint
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries: 3,
+	MinDelay:   500 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// delay - returns delay before retry with jitter, picked
+// from [d/2, d], where d is doubled with every attempt
+func (c RetryConfig) delay(attempt int) time.Duration {
+	d := c.MinDelay
+	for i := 0; i < attempt && d < c.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > c.MaxDelay {
+		d = c.MaxDelay
+	}
+
+	return jitter(d)
+}
+
+// jitter - returns random duration from [d/2, d]
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// rateLimitCodes - JSON-RPC error codes that providers use
+// when requests rate or compute units budget is exceeded
+var rateLimitCodes = map[int]bool{
+	429:    true,
+	-32005: true,
+	-32029: true,
+}
+
+// retryAfterKeys - fields of JSON-RPC error data that providers
+// use to tell how many seconds client should wait
+var retryAfterKeys = []string{
+	"backoff_seconds",
+	"retry_after",
+	"retryAfter",
+}
+
+// IsRateLimited - returns true if node refused request because
+// of requests rate or compute units limit
+func IsRateLimited(err error) bool {
+	_, ok := rateLimitHint(err)
+	return ok
+}
+
+// rateLimitHint - returns true if error means that request was
+// throttled by node, and time after which it could be repeated,
+// if node told it
+func rateLimitHint(err error) (time.Duration, bool) {
+	switch cause := errors.Cause(err).(type) {
+	case rpc.HTTPError:
+		// Retry-After header is not a part of error, it is
+		// remembered by endpoint transport instead
+		return 0, cause.StatusCode == http.StatusTooManyRequests
+	case rpc.Error:
+		if !rateLimitCodes[cause.ErrorCode()] {
+			return 0, false
+		}
+
+		// -32005 is also used for too wide eth_getLogs
+		// ranges, which are not fixed by waiting
+		if cause.ErrorCode() == -32005 && !strings.Contains(strings.ToLower(cause.Error()), "rate") {
+			return 0, false
+		}
+
+		dataErr, ok := cause.(rpc.DataError)
+		if !ok {
+			return 0, true
+		}
+
+		return retryAfter(dataErr.ErrorData()), true
+	default:
+		return 0, false
+	}
+}
+
+func retryAfter(data interface{}) time.Duration {
+	fields, err := cast.ToStringMapE(data)
+	if err != nil {
+		return 0
+	}
+
+	for _, key := range retryAfterKeys {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		seconds, err := cast.ToFloat64E(value)
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	return 0
+}
+
+// retryAfterTransport - remembers delay that endpoint asks to wait
+// with Retry-After header of throttled responses
+type retryAfterTransport struct {
+	endpoint *endpoint
+	next     http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if delay := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); delay > 0 {
+			t.endpoint.setRetryAfter(delay)
+		}
+	}
+
+	return resp, nil
+}
+
+// parseRetryAfter - returns delay of Retry-After header, that is
+// either number of seconds or HTTP date, or zero if it is invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+
+	return date.Sub(now)
+}
@@ -0,0 +1,103 @@
+package multiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// jsonError - JSON-RPC error as it is returned by rpc client
+type jsonError struct {
+	code    int
+	message string
+	data    interface{}
+}
+
+func (e jsonError) Error() string          { return e.message }
+func (e jsonError) ErrorCode() int         { return e.code }
+func (e jsonError) ErrorData() interface{} { return e.data }
+
+func Test_RateLimitHint(t *testing.T) {
+	t.Run("alchemy compute units", func(t *testing.T) {
+		err := jsonError{code: 429, message: "Your app has exceeded its compute units per second capacity"}
+
+		hint, ok := rateLimitHint(errors.Wrap(err, "failed to call contract"))
+		require.True(t, ok)
+		require.Zero(t, hint)
+	})
+
+	t.Run("infura backoff", func(t *testing.T) {
+		err := jsonError{
+			code:    -32005,
+			message: "project ID request rate exceeded",
+			data:    map[string]interface{}{"backoff_seconds": 1.5},
+		}
+
+		hint, ok := rateLimitHint(err)
+		require.True(t, ok)
+		require.Equal(t, 1500*time.Millisecond, hint)
+	})
+
+	t.Run("too many logs", func(t *testing.T) {
+		err := jsonError{code: -32005, message: "query returned more than 10000 results"}
+
+		_, ok := rateLimitHint(err)
+		require.False(t, ok)
+	})
+
+	t.Run("http status", func(t *testing.T) {
+		_, ok := rateLimitHint(rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"})
+		require.True(t, ok)
+
+		_, ok = rateLimitHint(rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"})
+		require.False(t, ok)
+	})
+}
+
+func Test_RetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("seconds", func(t *testing.T) {
+		require.Equal(t, 2*time.Second, parseRetryAfter("2", now))
+		require.Zero(t, parseRetryAfter("0", now))
+	})
+
+	t.Run("http date", func(t *testing.T) {
+		date := now.Add(3 * time.Second).Format(http.TimeFormat)
+		require.Equal(t, 3*time.Second, parseRetryAfter(date, now))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.Zero(t, parseRetryAfter("", now))
+		require.Zero(t, parseRetryAfter("soon", now))
+	})
+
+	t.Run("header of throttled response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		e := newEndpoint(EndpointConfig{URL: server.URL})
+
+		client, err := e.dial(context.Background())
+		require.NoError(t, err)
+		defer client.Close()
+
+		var head string
+		err = client.CallContext(context.Background(), &head, "eth_blockNumber")
+
+		_, ok := rateLimitHint(err)
+		require.True(t, ok)
+
+		delay := e.retryAfter()
+		require.True(t, delay > 4*time.Second && delay <= 5*time.Second)
+	})
+}